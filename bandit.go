@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FeedbackMutator is implemented by mutators that learn from the number of
+// new states found when running the traces they produced. Discard forgets the
+// traces waiting for feedback, the fuzzer drops the queued traces when it
+// reseeds.
+type FeedbackMutator interface {
+	Mutator
+	Feedback(trace *List[*SchedulingChoice], newStates int)
+	Discard()
+}
+
+// MutatorStats is the success statistics of one mutator of a BanditMutator
+type MutatorStats struct {
+	Name      string
+	Selected  int
+	Successes int
+	// Probability is the share of the selections that went to the mutator,
+	// the distribution learned by the bandit
+	Probability float64
+}
+
+type MutatorReporter interface {
+	MutatorStats() []MutatorStats
+}
+
+type BanditArm struct {
+	Name    string
+	Mutator Mutator
+}
+
+// BanditMutator picks one of its arms for every mutation with the UCB1
+// policy. An arm is rewarded when a trace it produced finds new states, so the
+// mutators and intensities that work for the guider are chosen more often.
+type BanditMutator struct {
+	Arms []BanditArm
+	// Exploration scales the confidence term of UCB1
+	Exploration float64
+
+	selected  []int
+	successes []int
+	total     int
+	last      int
+	pending   map[string]int
+	r         *rand.Rand
+}
+
+var _ FeedbackMutator = &BanditMutator{}
+var _ MutatorReporter = &BanditMutator{}
+var _ NamedMutator = &BanditMutator{}
+
+func NewBanditMutator(arms ...BanditArm) *BanditMutator {
+	return &BanditMutator{
+		Arms:        arms,
+		Exploration: math.Sqrt2,
+		selected:    make([]int, len(arms)),
+		successes:   make([]int, len(arms)),
+		pending:     make(map[string]int),
+		r:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Seed seeds the choice of the arm, and the mutator of every arm with a seed
+// drawn from it
+func (b *BanditMutator) Seed(seed int64) {
+	b.r = rand.New(rand.NewSource(seed))
+	for _, arm := range b.Arms {
+		seedRandom(arm.Mutator, b.r.Int63())
+	}
+}
+
+// DefaultBanditArms are the mutators of the compare command at a few
+// intensities each
+func DefaultBanditArms(config *FuzzerConfig) []BanditArm {
+	limits := NewTraceLimits(config)
+	replicas := make([]uint64, config.RaftEnvironmentConfig.Replicas)
+	for i := range replicas {
+		replicas[i] = uint64(i + 1)
+	}
+	arms := make([]BanditArm, 0)
+	for _, n := range []int{5, 20, 50} {
+		arms = append(arms, BanditArm{Name: fmt.Sprintf("swapNode(%d)", n), Mutator: NewSwapNodeMutator(n)})
+	}
+	for _, n := range []int{5, 20} {
+		arms = append(arms, BanditArm{Name: fmt.Sprintf("swapMaxMessages(%d)", n), Mutator: NewSwapMaxMessagesMutator(n)})
+	}
+	for _, n := range []int{1, 2} {
+		arms = append(arms, BanditArm{Name: fmt.Sprintf("swapCrashNode(%d)", n), Mutator: NewSwapCrashNodeMutator(n)})
+	}
+	return append(arms,
+		BanditArm{Name: "skipNode(3)", Mutator: NewSkipNodeMutator(3)},
+		BanditArm{Name: "crashLeader", Mutator: NewCrashLeaderMutator(5)},
+		BanditArm{Name: "delayVoteResponse(3)", Mutator: NewDelayVoteResponseMutator(3)},
+		BanditArm{Name: "duplicateAppend", Mutator: NewDuplicateAppendMutator()},
+		BanditArm{Name: "clientRequestTiming", Mutator: NewClientRequestTimingMutator()},
+		BanditArm{Name: "splice", Mutator: NewSpliceMutator(100)},
+		BanditArm{Name: "crashTiming(5)", Mutator: NewCrashTimingMutator(5, limits)},
+		BanditArm{Name: "crashPair(10)", Mutator: NewCrashPairMutator(replicas, 10, limits)},
+		BanditArm{Name: "requestTiming(10)", Mutator: NewRequestTimingMutator(10, limits)},
+		BanditArm{Name: "maxMessages(5)", Mutator: NewMaxMessagesMutator(5, limits)},
+	)
+}
+
+func traceKey(trace *List[*SchedulingChoice]) string {
+	bs, _ := json.Marshal(trace)
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:])
+}
+
+func (b *BanditMutator) pick() int {
+	best := -1
+	bestScore := 0.0
+	for _, i := range b.r.Perm(len(b.Arms)) {
+		if b.selected[i] == 0 {
+			return i
+		}
+		n := float64(b.selected[i])
+		score := float64(b.successes[i])/n + b.Exploration*math.Sqrt(math.Log(float64(b.total))/n)
+		if best == -1 || score > bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+	return best
+}
+
+func (b *BanditMutator) Mutate(trace *List[*SchedulingChoice], eventTrace *List[*Event]) (*List[*SchedulingChoice], bool) {
+	if len(b.Arms) == 0 {
+		return nil, false
+	}
+	arm := b.pick()
+	b.last = arm
+	b.selected[arm] += 1
+	b.total += 1
+	newTrace, ok := b.Arms[arm].Mutator.Mutate(trace, eventTrace)
+	if !ok {
+		return nil, false
+	}
+	b.pending[traceKey(newTrace)] = arm
+	return newTrace, true
+}
+
+func (b *BanditMutator) LastMutation() string {
+	if len(b.Arms) == 0 {
+		return "bandit"
+	}
+	return b.Arms[b.last].Name
+}
+
+func (b *BanditMutator) Feedback(trace *List[*SchedulingChoice], newStates int) {
+	key := traceKey(trace)
+	arm, ok := b.pending[key]
+	if !ok {
+		return
+	}
+	delete(b.pending, key)
+	if newStates > 0 {
+		b.successes[arm] += 1
+	}
+}
+
+func (b *BanditMutator) Discard() {
+	b.pending = make(map[string]int)
+}
+
+func (b *BanditMutator) Reset() {
+	b.selected = make([]int, len(b.Arms))
+	b.successes = make([]int, len(b.Arms))
+	b.total = 0
+	b.pending = make(map[string]int)
+	for _, a := range b.Arms {
+		resetMutator(a.Mutator)
+	}
+}
+
+func (b *BanditMutator) MutatorStats() []MutatorStats {
+	stats := make([]MutatorStats, len(b.Arms))
+	for i, a := range b.Arms {
+		stats[i] = MutatorStats{
+			Name:      a.Name,
+			Selected:  b.selected[i],
+			Successes: b.successes[i],
+		}
+		if b.total > 0 {
+			stats[i].Probability = float64(b.selected[i]) / float64(b.total)
+		}
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].Probability > stats[j].Probability
+	})
+	return stats
+}
+
+func formatMutatorStats(stats []MutatorStats) string {
+	b := &strings.Builder{}
+	for _, s := range stats {
+		fmt.Fprintf(b, "%-24s p=%.3f selected=%d successes=%d\n", s.Name, s.Probability, s.Selected, s.Successes)
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+)
+
+// tagMutator appends a choice with a fresh tag so that every offspring is a
+// distinct trace
+type tagMutator struct {
+	fail bool
+	tag  int
+}
+
+func (m *tagMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
+	if m.fail {
+		return nil, false
+	}
+	m.tag++
+	newTrace := copyTrace(trace, defaultCopyFilter())
+	newTrace.Append(&SchedulingChoice{Type: RandomInteger, IntegerChoice: m.tag})
+	return newTrace, true
+}
+
+func TestBanditMutator(t *testing.T) {
+	good := &tagMutator{}
+	b := NewBanditMutator(
+		BanditArm{Name: "good", Mutator: good},
+		BanditArm{Name: "bad", Mutator: &tagMutator{}},
+		BanditArm{Name: "failing", Mutator: &tagMutator{fail: true}},
+	)
+	parent := NewList[*SchedulingChoice]()
+	for i := 0; i < 500; i++ {
+		tag := good.tag
+		offspring, ok := b.Mutate(parent, NewList[*Event]())
+		if !ok {
+			continue
+		}
+		newStates := 0
+		if good.tag != tag {
+			newStates = 1
+		}
+		b.Feedback(copyTrace(offspring, defaultCopyFilter()), newStates)
+	}
+
+	stats := b.MutatorStats()
+	if stats[0].Name != "good" || stats[0].Probability < 0.5 {
+		t.Errorf("expected the rewarded mutator to be chosen most, got %v", stats)
+	}
+	total := 0.0
+	for _, s := range stats {
+		total += s.Probability
+	}
+	if total < 0.99 || total > 1.01 {
+		t.Errorf("expected a distribution, got a total of %f", total)
+	}
+
+	b.Reset()
+	if stats := b.MutatorStats(); stats[0].Selected != 0 || len(b.pending) != 0 {
+		t.Errorf("expected the statistics to be cleared, got %v", stats)
+	}
+}
+
+func TestBanditMutatorFuzzer(t *testing.T) {
+	config := testFuzzerConfig(NewNativeStateGuider(DefaultAbstraction(), "", false), nil)
+	b := NewBanditMutator(DefaultBanditArms(config)...)
+	config.Mutator = b
+	f := NewFuzzer(config)
+	f.Run()
+	stats := f.stats.Snapshot().Mutators
+	if len(stats) == 0 {
+		t.Fatal("expected the mutator statistics in the fuzzer stats")
+	}
+	selected := 0
+	for _, s := range stats {
+		selected += s.Selected
+	}
+	if selected == 0 {
+		t.Error("expected mutators to be selected")
+	}
+	// The traces dropped at a reseed are not waiting for feedback
+	if len(b.pending) > f.mutatedTracesQueue.Size() {
+		t.Errorf("expected at most the %d queued traces to wait for feedback, got %d", f.mutatedTracesQueue.Size(), len(b.pending))
+	}
+}
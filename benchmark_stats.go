@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// significance is the level below which a p-value is reported significant
+const significance = 0.05
+
+const (
+	metricFinalCoverage     = "final_coverage"
+	metricTimeToFirstBug    = "time_to_first_bug"
+	metricSecondsToFirstBug = "seconds_to_first_bug"
+	metricBugRate           = "bugs_per_1000_iterations"
+	metricBugSignatures     = "unique_bug_signatures"
+)
+
+var comparisonMetrics = []string{metricFinalCoverage, metricTimeToFirstBug, metricSecondsToFirstBug, metricBugRate, metricBugSignatures}
+
+// PairStatistics compares a metric of two benchmarks over their runs. A12 is
+// the probability that a run of the first benchmark has the larger value,
+// lower is better for the time to the first bug.
+type PairStatistics struct {
+	U           float64
+	P           float64
+	A12         float64
+	Effect      string
+	Significant bool
+}
+
+func comparePair(xs, ys []float64) PairStatistics {
+	if len(xs) == 0 || len(ys) == 0 {
+		return PairStatistics{P: 1, A12: 0.5, Effect: "-"}
+	}
+	u, p := mannWhitneyU(xs, ys)
+	a12 := varghaDelaneyA12(xs, ys)
+	return PairStatistics{
+		U:           u,
+		P:           p,
+		A12:         a12,
+		Effect:      effectSize(a12),
+		Significant: p < significance,
+	}
+}
+
+// firstBugIteration returns the first iteration with a buggy execution, -1
+// when there is none
+func firstBugIteration(records []BugRecord) int {
+	if len(records) > 0 {
+		return records[0].Iteration
+	}
+	return -1
+}
+
+func uniqueSignatures(records []BugRecord) map[string]int {
+	signatures := make(map[string]int)
+	for _, r := range records {
+		signatures[r.Signature]++
+	}
+	return signatures
+}
+
+func (c *Comparision) iterations(name string) int {
+	if b, ok := c.benchmarks[name]; ok && b.config != nil {
+		return b.config.Iterations
+	}
+	return c.config.Iterations
+}
+
+// metricSamples returns the value of every metric for every run of the
+// benchmarks, and for the times to the first bug whether the run found one.
+// A run without a bug takes the number of iterations, or its run time, as
+// its time, which ranks it behind the runs that found one.
+func (c *Comparision) metricSamples(finalCoverages map[string][]CoverageStats) (map[string]map[string][]float64, map[string]map[string][]bool) {
+	samples := make(map[string]map[string][]float64)
+	found := make(map[string]map[string][]bool)
+	for _, metric := range comparisonMetrics {
+		samples[metric] = make(map[string][]float64)
+		found[metric] = make(map[string][]bool)
+	}
+	add := func(metric, name string, value float64, ok bool) {
+		samples[metric][name] = append(samples[metric][name], value)
+		found[metric][name] = append(found[metric][name], ok)
+	}
+	for name, coverages := range finalCoverages {
+		for _, cov := range coverages {
+			add(metricFinalCoverage, name, float64(cov.UniqueStates), true)
+		}
+	}
+	for _, rI := range c.runInfos {
+		for name, stats := range rI.stats {
+			records := stats.Bugs
+			iterations := c.iterations(name)
+			if first := firstBugIteration(records); first != -1 {
+				add(metricTimeToFirstBug, name, float64(first), true)
+				add(metricSecondsToFirstBug, name, records[0].Elapsed.Seconds(), true)
+			} else {
+				add(metricTimeToFirstBug, name, float64(iterations), false)
+				add(metricSecondsToFirstBug, name, rI.runTimes[name].Seconds(), false)
+			}
+			add(metricBugRate, name, 1000*float64(len(records))/float64(max(iterations, 1)), true)
+			add(metricBugSignatures, name, float64(len(uniqueSignatures(records))), true)
+		}
+	}
+	return samples, found
+}
+
+// recordStatistics adds the summaries of the runs and the pairwise tests of
+// every metric to the record, and writes them as summary.csv,
+// comparisons.csv and summary.md
+func (c *Comparision) recordStatistics(recordData map[string]map[string]interface{}, finalCoverages map[string][]CoverageStats) {
+	samples, found := c.metricSamples(finalCoverages)
+	metrics := comparisonMetrics
+	names := make([]string, 0, len(recordData))
+	for name := range recordData {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summaries := make(map[string]map[string]Summary)
+	for _, metric := range metrics {
+		summaries[metric] = make(map[string]Summary)
+		for _, name := range names {
+			// Only the runs that found a bug have a time to it
+			values := make([]float64, 0, len(samples[metric][name]))
+			for i, v := range samples[metric][name] {
+				if found[metric][name][i] {
+					values = append(values, v)
+				}
+			}
+			if metric == metricTimeToFirstBug {
+				recordData[name]["runs_with_bug"] = len(values)
+			}
+			summaries[metric][name] = summarize(values)
+			recordData[name][metric] = summaries[metric][name]
+		}
+	}
+
+	for _, name := range names {
+		signatures := make(map[string]int)
+		total := 0
+		for _, rI := range c.runInfos {
+			for signature, count := range uniqueSignatures(rI.stats[name].Bugs) {
+				signatures[signature] += count
+				total += count
+			}
+		}
+		recordData[name]["bug_signatures"] = signatures
+		if total > 0 {
+			fmt.Printf("%s found %d bugs with %d signatures, %.2f per 1000 iterations, the first after %.1f iterations on average\n",
+				name, total, len(signatures), summaries[metricBugRate][name].Mean, summaries[metricTimeToFirstBug][name].Mean)
+		}
+	}
+
+	comparisons := make(map[string]map[string]map[string]PairStatistics)
+	for _, a := range names {
+		comparisons[a] = make(map[string]map[string]PairStatistics)
+		for _, b := range names {
+			if a == b {
+				continue
+			}
+			comparisons[a][b] = make(map[string]PairStatistics)
+			for _, metric := range metrics {
+				comparisons[a][b][metric] = comparePair(samples[metric][a], samples[metric][b])
+			}
+		}
+		recordData[a]["comparisons"] = comparisons[a]
+	}
+
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			for _, metric := range metrics {
+				if pair := comparisons[a][b][metric]; pair.Significant {
+					fmt.Printf("%s vs %s: %s differs, A12=%.2f (%s), p=%.4f\n", a, b, metric, pair.A12, pair.Effect, pair.P)
+				}
+			}
+		}
+	}
+
+	if err := c.writeStatistics(names, metrics, summaries, comparisons); err != nil {
+		fmt.Printf("Error writing the summary: %s\n", err)
+	}
+}
+
+func formatFloat(f float64) string {
+	if math.IsNaN(f) {
+		return "-"
+	}
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+func (c *Comparision) writeStatistics(names, metrics []string, summaries map[string]map[string]Summary, comparisons map[string]map[string]map[string]PairStatistics) error {
+	summaryRows := [][]string{{"metric", "benchmark", "n", "mean", "ci_low", "ci_high", "median", "q1", "q3", "iqr"}}
+	for _, metric := range metrics {
+		for _, name := range names {
+			s := summaries[metric][name]
+			row := []string{metric, name, strconv.Itoa(s.N)}
+			for _, v := range []float64{s.Mean, s.CILow, s.CIHigh, s.Median, s.Q1, s.Q3, s.IQR} {
+				if s.N == 0 {
+					v = math.NaN()
+				}
+				row = append(row, formatFloat(v))
+			}
+			summaryRows = append(summaryRows, row)
+		}
+	}
+	pairRows := [][]string{{"metric", "a", "b", "u", "p", "a12", "effect", "significant"}}
+	for _, metric := range metrics {
+		for i, a := range names {
+			for _, b := range names[i+1:] {
+				pair := comparisons[a][b][metric]
+				pairRows = append(pairRows, []string{metric, a, b, formatFloat(pair.U), strconv.FormatFloat(pair.P, 'g', 4, 64), formatFloat(pair.A12), pair.Effect, strconv.FormatBool(pair.Significant)})
+			}
+		}
+	}
+
+	if err := writeCSV(path.Join(c.plotPath, "summary.csv"), summaryRows); err != nil {
+		return err
+	}
+	if err := writeCSV(path.Join(c.plotPath, "comparisons.csv"), pairRows); err != nil {
+		return err
+	}
+	md := &strings.Builder{}
+	fmt.Fprintf(md, "# Comparison over %d runs\n\n", c.runs)
+	fmt.Fprintf(md, "Confidence intervals are 95%% intervals of the mean. The times to the first bug, in iterations and seconds, are over the runs that found a bug.\n\n")
+	writeMarkdownTable(md, summaryRows)
+	fmt.Fprintf(md, "\n## Pairwise comparisons\n\nTwo sided Mann-Whitney U tests, significant when p < %.2f. A12 is the probability that a run of a has the larger value, runs without a bug take the number of iterations or their run time as their time to the first bug.\n\n", significance)
+	writeMarkdownTable(md, pairRows)
+	return os.WriteFile(path.Join(c.plotPath, "summary.md"), []byte(md.String()), 0644)
+}
+
+func writeCSV(filePath string, rows [][]string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+func writeMarkdownTable(b *strings.Builder, rows [][]string) {
+	for i, row := range rows {
+		fmt.Fprintf(b, "| %s |\n", strings.Join(row, " | "))
+		if i == 0 {
+			fmt.Fprintf(b, "|%s\n", strings.Repeat(" --- |", len(row)))
+		}
+	}
+}
@@ -19,18 +19,34 @@ type Comparision struct {
 	plotPath   string
 	runs       int
 	runInfos   []runInfo
+	// seeds are the seeds of the raft environment of every run
+	seeds []int64
+	// status serves the progress of the runs when set
+	status *StatusServer
 }
 
 type benchmark struct {
-	guider  Guider
-	mutator Mutator
-	key     string
+	guider   Guider
+	mutator  Mutator
+	strategy Strategy
+	key      string
+	// config replaces the config of the comparison when set
+	config *FuzzerConfig
+	// build creates the strategy and the mutator of every run, seeded with
+	// the seed of the run, when set
+	build func(seed int64) (Strategy, Mutator)
 }
 
 type runInfo struct {
 	runTimes  map[string]time.Duration
 	coverages map[string][]CoverageStats
-	stats     map[string]map[string]interface{}
+	stats     map[string]*FuzzerStats
+	// elapsed is the wall-clock time at the end of every iteration
+	elapsed map[string][]time.Duration
+	// bugs counts the buggy executions up to every iteration and signatures
+	// the distinct signatures of the bugs
+	bugs       map[string][]int
+	signatures map[string][]int
 }
 
 func NewComparision(plotPath string, config *FuzzerConfig, runs int) *Comparision {
@@ -51,33 +67,87 @@ func NewComparision(plotPath string, config *FuzzerConfig, runs int) *Comparisio
 }
 
 func (c *Comparision) Add(name string, mutator Mutator, guider Guider) {
+	c.AddWithStrategy(name, c.config.Strategy, mutator, guider)
+}
+
+func (c *Comparision) AddWithStrategy(name string, strategy Strategy, mutator Mutator, guider Guider) {
 	c.benchmarks[name] = benchmark{
-		guider:  guider,
-		mutator: mutator,
-		key:     name,
+		guider:   guider,
+		mutator:  mutator,
+		strategy: strategy,
+		key:      name,
 	}
 }
 
+// AddWithConfig adds a benchmark run with its own config, the strategy and
+// the mutator of every run are created by build from the seed of the run
+func (c *Comparision) AddWithConfig(name string, config *FuzzerConfig, guider Guider, build func(seed int64) (Strategy, Mutator)) {
+	c.benchmarks[name] = benchmark{
+		guider: guider,
+		key:    name,
+		config: config,
+		build:  build,
+	}
+}
+
+// SetSeeds seeds the runs, one seed per run. The seed of a run seeds the raft
+// environment and the strategies and mutators of the benchmarks added with
+// their config.
+func (c *Comparision) SetSeeds(seeds []int64) {
+	c.seeds = seeds
+}
+
+// SetStatus reports the progress of the runs to the status server
+func (c *Comparision) SetStatus(status *StatusServer) {
+	c.status = status
+}
+
 func (c *Comparision) doRun(run int) runInfo {
 	fmt.Printf("Starting run %d...\n", run+1)
 	rI := runInfo{
-		runTimes:  make(map[string]time.Duration),
-		coverages: make(map[string][]CoverageStats),
-		stats:     make(map[string]map[string]interface{}),
+		runTimes:   make(map[string]time.Duration),
+		coverages:  make(map[string][]CoverageStats),
+		stats:      make(map[string]*FuzzerStats),
+		elapsed:    make(map[string][]time.Duration),
+		bugs:       make(map[string][]int),
+		signatures: make(map[string][]int),
 	}
 	for key, b := range c.benchmarks {
-		c.config.Guider = b.guider
-		c.config.Mutator = b.mutator
+		config := c.config
+		if b.config != nil {
+			config = b.config
+		}
+		var seed int64
+		if run < len(c.seeds) {
+			seed = c.seeds[run]
+		}
+		strategy, mutator := b.strategy, b.mutator
+		if b.build != nil {
+			strategy, mutator = b.build(seed)
+		}
+		config.Guider = b.guider
+		setGuiderName(b.guider, key)
+		config.Mutator = mutator
+		config.Strategy = strategy
+		config.RaftEnvironmentConfig.Seed = seed
+		config.Observer = nil
+		if c.status != nil {
+			config.Observer = c.status.Observer(key, run)
+		}
 		rI.coverages[key] = make([]CoverageStats, 0)
-		fuzzer := NewFuzzer(c.config)
+		fuzzer := NewFuzzer(config)
 		start := time.Now()
 		fmt.Printf("Running for benchmark: %s\n", key)
 		rI.coverages[key] = fuzzer.Run()
 		end := time.Since(start)
 		rI.runTimes[key] = end
 		fmt.Printf("\nRun time: %s\n", end.String())
-		rI.stats[key] = fuzzer.stats
+		rI.stats[key] = fuzzer.stats.Snapshot()
+		rI.elapsed[key] = fuzzer.elapsed
+		rI.bugs[key] = cumulativeBugs(rI.stats[key].Bugs, len(rI.coverages[key]))
+		rI.signatures[key] = cumulativeSignatures(rI.stats[key].Bugs, len(rI.coverages[key]))
 		b.guider.Reset(key)
+		resetMutator(mutator)
 	}
 	return rI
 }
@@ -96,7 +166,7 @@ func (c *Comparision) record() {
 	runTimes := make(map[string][]time.Duration)
 	finalCoverages := make(map[string][]CoverageStats)
 	uniqueStateCoverages := make(map[string][][]int)
-	stats := make(map[string][]map[string]interface{})
+	stats := make(map[string][]*FuzzerStats)
 
 	for i := 0; i < c.runs; i++ {
 		plotFile := path.Join(c.plotPath, fmt.Sprintf("%d.png", i))
@@ -149,7 +219,7 @@ func (c *Comparision) record() {
 
 		for name, rStats := range c.runInfos[i].stats {
 			if _, ok := stats[name]; !ok {
-				stats[name] = make([]map[string]interface{}, 0)
+				stats[name] = make([]*FuzzerStats, 0)
 			}
 			stats[name] = append(stats[name], rStats)
 		}
@@ -176,14 +246,41 @@ func (c *Comparision) record() {
 			sum.UniqueStates += cov.UniqueStates
 			sum.UniqueStateTraces += cov.UniqueStateTraces
 			sum.UniqueTraces += cov.UniqueTraces
+			sum.Divergences += cov.Divergences
+			sum.UniqueEventTraces += cov.UniqueEventTraces
+			sum.CoveredLines += cov.CoveredLines
+			sum.CoveredEdges += cov.CoveredEdges
+			sum.EdgeFeatures += cov.EdgeFeatures
+			sum.FallbackEpisodes += cov.FallbackEpisodes
+			sum.FallbackStates += cov.FallbackStates
+			sum.FailedEpisodes += cov.FailedEpisodes
 		}
 		avg := CoverageStats{
 			UniqueStates:      sum.UniqueStates / len(coverages),
 			UniqueStateTraces: sum.UniqueStateTraces / len(coverages),
 			UniqueTraces:      sum.UniqueTraces / len(coverages),
+			Divergences:       sum.Divergences / len(coverages),
+			UniqueEventTraces: sum.UniqueEventTraces / len(coverages),
+			CoveredLines:      sum.CoveredLines / len(coverages),
+			CoveredEdges:      sum.CoveredEdges / len(coverages),
+			EdgeFeatures:      sum.EdgeFeatures / len(coverages),
+			FallbackEpisodes:  sum.FallbackEpisodes / len(coverages),
+			FallbackStates:    sum.FallbackStates / len(coverages),
+			FailedEpisodes:    sum.FailedEpisodes / len(coverages),
 		}
 		recordData[name]["average_coverage"] = avg
 		fmt.Printf("Final average state coverage of %s is %d\n", name, avg.UniqueStates)
+		if sum.Divergences > 0 {
+			fmt.Printf("%s found %d model/implementation divergences\n", name, sum.Divergences)
+		}
+		if sum.FallbackEpisodes > 0 {
+			fmt.Printf("%s checked %d episodes with native abstract states while TLC was unreachable, they are not part of its state coverage\n", name, sum.FallbackEpisodes)
+		}
+		recordData[name]["fallback_episodes"] = sum.FallbackEpisodes
+		if sum.FailedEpisodes > 0 {
+			fmt.Printf("%s could not check %d episodes, TLC was unreachable\n", name, sum.FailedEpisodes)
+		}
+		recordData[name]["failed_episodes"] = sum.FailedEpisodes
 		recordData[name]["coverages"] = coverages
 	}
 	for name, kStats := range stats {
@@ -193,6 +290,9 @@ func (c *Comparision) record() {
 		recordData[name]["coverages"] = coverages
 	}
 
+	c.recordStatistics(recordData, finalCoverages)
+	c.plotAggregates()
+
 	recordPath := path.Join(c.plotPath, "data.json")
 
 	if cov, err := json.Marshal(recordData); err == nil {
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestComparision(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	savePath := path.Join(t.TempDir(), "results")
+	config := testFuzzerConfig(nil, nil)
+	config.Iterations = 10
+
+	c := NewComparision(savePath, config, 2)
+	mutator := CombineMutators(NewSwapCrashNodeMutator(1), NewSwapNodeMutator(5), NewSwapMaxMessagesMutator(5))
+	c.Add("tlcstate", mutator, NewTLCStateGuider(tlc.Client(), "", false))
+	c.Add("traceCov", mutator, NewTraceCoverageGuider(tlc.Client(), "", false))
+	c.Add("random", &EmptyMutator{}, NewTLCStateGuider(tlc.Client(), "", false))
+	c.AddWithStrategy("pct", NewPCTStrategy(3), &EmptyMutator{}, NewNativeStateGuider(DefaultAbstraction(), "", false))
+	c.Run()
+
+	for _, file := range []string{"0.png", "1.png", "data.json", "summary.csv", "comparisons.csv", "summary.md",
+		"unique_states.png", "unique_states.svg", "unique_traces.svg", "unique_state_traces.png", "unique_states_time.png", "bugs.svg", "bug_signatures.png"} {
+		if _, err := os.Stat(path.Join(savePath, file)); err != nil {
+			t.Errorf("expected %s to be written: %s", file, err)
+		}
+	}
+	bs, err := os.ReadFile(path.Join(savePath, "data.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make(map[string]map[string]interface{})
+	if err := json.Unmarshal(bs, &data); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"tlcstate", "traceCov", "random", "pct"} {
+		for _, key := range []string{"average_coverage", "final_coverage", "time_to_first_bug", "seconds_to_first_bug", "bugs_per_1000_iterations", "unique_bug_signatures", "bug_signatures", "comparisons"} {
+			if _, ok := data[name][key]; !ok {
+				t.Errorf("expected %s of %s", key, name)
+			}
+		}
+	}
+}
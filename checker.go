@@ -7,49 +7,72 @@ import (
 	pb "github.com/zeu5/raft-fuzzing/raft/raftpb"
 )
 
-type Checker func(*RaftEnvironment) bool
-
-func SerializabilityChecker() func(*RaftEnvironment) bool {
-	return func(re *RaftEnvironment) bool {
-		minCommit := 100
-		for _, state := range re.curStates {
-			if state.Commit < uint64(minCommit) {
-				minCommit = int(state.Commit)
-			}
-		}
-		if minCommit == 0 {
-			return true
+// Checker tells why the environment violates a property, it returns an empty
+// reason when the property holds
+type Checker func(*RaftEnvironment) string
+
+func SerializabilityChecker() Checker {
+	return serializabilityViolation
+}
+
+// serializabilityViolation tells how the committed logs of the nodes
+// disagree, empty when they agree
+func serializabilityViolation(re *RaftEnvironment) string {
+	minCommit := 100
+	for _, state := range re.curStates {
+		if state.Commit < uint64(minCommit) {
+			minCommit = int(state.Commit)
 		}
-		logs := make([][]pb.Entry, 0)
-		for _, storage := range re.storages {
-			l, err := storage.Entries(1, uint64(minCommit)+1, 100)
-			if err != nil {
-				return false
-			}
-			logs = append(logs, l)
+	}
+	if minCommit == 0 {
+		return ""
+	}
+	logs := make([][]pb.Entry, 0)
+	for _, storage := range re.storages {
+		l, err := storage.Entries(1, uint64(minCommit)+1, 100)
+		if err != nil {
+			return "missing_entries"
 		}
+		logs = append(logs, l)
+	}
 
-		for i := 0; i < minCommit; i++ {
-			l := logs[0][i]
-			for j := 1; j < len(logs); j++ {
-				cur := logs[j][i]
-				if cur.Term != l.Term || cur.Index != l.Index || !bytes.Equal(cur.Data, l.Data) {
-					return false
-				}
+	for i := 0; i < minCommit; i++ {
+		l := logs[0][i]
+		for j := 1; j < len(logs); j++ {
+			cur := logs[j][i]
+			switch {
+			case cur.Term != l.Term:
+				return "term_mismatch"
+			case cur.Index != l.Index:
+				return "index_mismatch"
+			case !bytes.Equal(cur.Data, l.Data):
+				return "data_mismatch"
 			}
 		}
-		return true
 	}
+	return ""
+}
+
+// bugSignature groups the failures of the checker by the reason the checker
+// gave and the roles and relative terms of the nodes at the end of the episode
+func bugSignature(reason string, last *EnvState) string {
+	if last == nil {
+		return reason
+	}
+	return reason + ";" + TermDiffAbstraction()(last)
 }
 
-func SingleLeader() func(*RaftEnvironment) bool {
-	return func(re *RaftEnvironment) bool {
+func SingleLeader() Checker {
+	return func(re *RaftEnvironment) string {
 		leaders := 0
 		for _, s := range re.curStates {
 			if s.RaftState == raft.StateLeader {
 				leaders += 1
 			}
 		}
-		return leaders <= 1
+		if leaders > 1 {
+			return "multiple_leaders"
+		}
+		return ""
 	}
 }
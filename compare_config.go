@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ConfigOverrides changes the parameters of the fuzzer, fields left out keep
+// the defaults of the compare command
+type ConfigOverrides struct {
+	Replicas           *int `json:"replicas,omitempty"`
+	ElectionTick       *int `json:"election_tick,omitempty"`
+	HeartbeatTick      *int `json:"heartbeat_tick,omitempty"`
+	TicksPerStep       *int `json:"ticks_per_step,omitempty"`
+	MutPerTrace        *int `json:"mut_per_trace,omitempty"`
+	NumberRequests     *int `json:"requests,omitempty"`
+	CrashQuota         *int `json:"crash_quota,omitempty"`
+	MaxMessages        *int `json:"max_messages,omitempty"`
+	SeedPopulationSize *int `json:"seed_population_size,omitempty"`
+	ReseedFrequency    *int `json:"reseed_frequency,omitempty"`
+}
+
+func (o *ConfigOverrides) apply(config *FuzzerConfig) {
+	if o == nil {
+		return
+	}
+	set := func(field *int, value *int) {
+		if value != nil {
+			*field = *value
+		}
+	}
+	set(&config.RaftEnvironmentConfig.Replicas, o.Replicas)
+	set(&config.RaftEnvironmentConfig.ElectionTick, o.ElectionTick)
+	set(&config.RaftEnvironmentConfig.HeartbeatTick, o.HeartbeatTick)
+	set(&config.RaftEnvironmentConfig.TicksPerStep, o.TicksPerStep)
+	set(&config.MutPerTrace, o.MutPerTrace)
+	set(&config.NumberRequests, o.NumberRequests)
+	set(&config.CrashQuota, o.CrashQuota)
+	set(&config.MaxMessages, o.MaxMessages)
+	set(&config.SeedPopulationSize, o.SeedPopulationSize)
+	set(&config.ReseedFrequency, o.ReseedFrequency)
+}
+
+// BenchmarkConfig declares one benchmark of a comparison
+type BenchmarkConfig struct {
+	Name string `json:"name"`
+	// Guider is one of tlc, trace, line, edge, native and multi
+	Guider string `json:"guider"`
+	// Weights are the guiders combined by the multi guider, as name=weight
+	Weights []string `json:"weights,omitempty"`
+	// Abstraction is the state abstraction of the native guider
+	Abstraction string `json:"abstraction,omitempty"`
+	// Mutator is one of none, combined, bandit, events and splice
+	Mutator string `json:"mutator,omitempty"`
+	// Strategy is one of random, roundrobin, delay, pos and pct, the
+	// strategy of the command line when left out
+	Strategy    string           `json:"strategy,omitempty"`
+	DelayBound  *int             `json:"delay_bound,omitempty"`
+	PCTDepth    *int             `json:"pct_depth,omitempty"`
+	Environment *ConfigOverrides `json:"environment,omitempty"`
+}
+
+// ExperimentConfig describes a comparison. Episodes, horizon and runs left
+// out are taken from the command line, the environment overrides apply to
+// every benchmark before its own.
+//
+//	{
+//	  "episodes": 10000,
+//	  "runs": 5,
+//	  "seeds": [1, 2, 3, 4, 5],
+//	  "environment": {"election_tick": 10, "crash_quota": 4},
+//	  "benchmarks": [
+//	    {"name": "tlcstate", "guider": "tlc", "mutator": "combined"},
+//	    {"name": "random", "guider": "tlc", "mutator": "none"},
+//	    {"name": "pct", "guider": "tlc", "mutator": "none", "strategy": "pct", "pct_depth": 4}
+//	  ]
+//	}
+type ExperimentConfig struct {
+	Episodes int `json:"episodes,omitempty"`
+	Horizon  int `json:"horizon,omitempty"`
+	Runs     int `json:"runs,omitempty"`
+	// Seeds are the seeds of the raft environment of every run, the runs
+	// without one are not seeded
+	Seeds       []int64           `json:"seeds,omitempty"`
+	Environment *ConfigOverrides  `json:"environment,omitempty"`
+	Benchmarks  []BenchmarkConfig `json:"benchmarks"`
+}
+
+func readExperimentConfig(configPath string) (*ExperimentConfig, error) {
+	switch filepath.Ext(configPath) {
+	case ".yaml", ".yml":
+		return nil, fmt.Errorf("only JSON experiment configs are supported: %s", configPath)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading experiment config: %s", err)
+	}
+	return parseExperimentConfig(data)
+}
+
+func parseExperimentConfig(data []byte) (*ExperimentConfig, error) {
+	config := &ExperimentConfig{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Catches misspelled parameters, which would silently keep the defaults
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("error parsing experiment config: %s", err)
+	}
+	if len(config.Benchmarks) == 0 {
+		return nil, fmt.Errorf("the experiment config has no benchmarks")
+	}
+	names := make(map[string]bool)
+	for i, b := range config.Benchmarks {
+		if b.Name == "" {
+			return nil, fmt.Errorf("benchmark %d has no name", i)
+		}
+		if names[b.Name] {
+			return nil, fmt.Errorf("duplicate benchmark: %s", b.Name)
+		}
+		names[b.Name] = true
+		if b.Guider == "" {
+			return nil, fmt.Errorf("benchmark %s has no guider", b.Name)
+		}
+	}
+	if config.Runs > 0 && len(config.Seeds) > config.Runs {
+		return nil, fmt.Errorf("%d seeds given for %d runs", len(config.Seeds), config.Runs)
+	}
+	return config, nil
+}
+
+// getCompareMutator returns the mutator of a benchmark by name, seeded with
+// the seed unless it is zero
+func getCompareMutator(name string, config *FuzzerConfig, seed int64) (Mutator, error) {
+	combined := func() Mutator {
+		return CombineMutators(NewSwapCrashNodeMutator(2), NewSwapNodeMutator(20), NewSwapMaxMessagesMutator(20))
+	}
+	var mutator Mutator
+	switch name {
+	case "", "none":
+		return &EmptyMutator{}, nil
+	case "combined":
+		mutator = combined()
+	case "bandit":
+		mutator = NewBanditMutator(DefaultBanditArms(config)...)
+	case "events":
+		mutator = NewChooseMutator(combined(), NewEventMutator())
+	case "splice":
+		mutator = NewChooseMutator(combined(), NewSpliceMutator(100))
+	default:
+		return nil, fmt.Errorf("unknown mutator: %s", name)
+	}
+	seedRandom(mutator, seed)
+	return mutator, nil
+}
+
+// addBenchmarks adds the benchmarks of the experiment to the comparison,
+// each with its own copy of the base config, their guiders record to
+// recordPath
+func (e *ExperimentConfig) addBenchmarks(c *Comparision, base *FuzzerConfig, tlcClient *TLCClient, recordPath string) error {
+	e.Environment.apply(base)
+	for _, b := range e.Benchmarks {
+		config := *base
+		b.Environment.apply(&config)
+
+		name, bound, depth := b.Strategy, delayBound, pctDepth
+		if b.DelayBound != nil {
+			bound = *b.DelayBound
+		}
+		if b.PCTDepth != nil {
+			depth = *b.PCTDepth
+		}
+		replicas := config.RaftEnvironmentConfig.Replicas
+		if _, err := GetStrategy(name, replicas, bound, depth, 0); name != "" && err != nil {
+			return fmt.Errorf("benchmark %s: %s", b.Name, err)
+		}
+		mutatorName := b.Mutator
+		if _, err := getCompareMutator(mutatorName, &config, 0); err != nil {
+			return fmt.Errorf("benchmark %s: %s", b.Name, err)
+		}
+
+		var guider Guider
+		var err error
+		switch {
+		case b.Guider == "multi":
+			guider, err = getMultiGuider(b.Weights, tlcClient, recordPath)
+		case b.Guider == "native" && b.Abstraction != "":
+			var a StateAbstraction
+			if a, err = GetAbstraction(b.Abstraction); err == nil {
+				guider = NewNativeStateGuider(a, recordPath, recordTraces)
+			}
+		default:
+			guider, err = getGuider(b.Guider, tlcClient, recordPath)
+		}
+		if err != nil {
+			return fmt.Errorf("benchmark %s: %s", b.Name, err)
+		}
+		c.AddWithConfig(b.Name, &config, guider, func(seed int64) (Strategy, Mutator) {
+			// Without a strategy of its own the benchmark reseeds the one of
+			// the base config
+			s := base.Strategy
+			if name != "" {
+				s, _ = GetStrategy(name, replicas, bound, depth, seed)
+			} else {
+				seedRandom(s, seed)
+			}
+			m, _ := getCompareMutator(mutatorName, &config, seed)
+			return s, m
+		})
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseExperimentConfig(t *testing.T) {
+	config, err := parseExperimentConfig([]byte(`{
+		"episodes": 50,
+		"runs": 2,
+		"seeds": [3, 4],
+		"environment": {"election_tick": 12, "crash_quota": 4},
+		"benchmarks": [
+			{"name": "tlcstate", "guider": "tlc", "mutator": "combined"},
+			{"name": "pct", "guider": "native", "abstraction": "roles-terms", "strategy": "pct", "pct_depth": 2, "environment": {"replicas": 5}},
+			{"name": "multi", "guider": "multi", "weights": ["tlc=2", "trace=1"], "mutator": "bandit"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Episodes != 50 || config.Runs != 2 || len(config.Seeds) != 2 || len(config.Benchmarks) != 3 {
+		t.Fatalf("unexpected experiment config: %+v", config)
+	}
+
+	tlc := newMockTLCServer(t)
+	base := testFuzzerConfig(nil, nil)
+	c := NewComparision("", base, config.Runs)
+	if err := config.addBenchmarks(c, base, tlc.Client(), t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.benchmarks) != 3 {
+		t.Fatalf("expected 3 benchmarks, got %d", len(c.benchmarks))
+	}
+	tlcstate, pct := c.benchmarks["tlcstate"], c.benchmarks["pct"]
+	if tlcstate.config.RaftEnvironmentConfig.ElectionTick != 12 || tlcstate.config.CrashQuota != 4 || tlcstate.config.RaftEnvironmentConfig.Replicas != 3 {
+		t.Errorf("expected the environment overrides of the experiment: %+v", tlcstate.config)
+	}
+	if pct.config.RaftEnvironmentConfig.Replicas != 5 || pct.config.RaftEnvironmentConfig.ElectionTick != 12 {
+		t.Errorf("expected the environment overrides of the benchmark: %+v", pct.config.RaftEnvironmentConfig)
+	}
+	strategy, mutator := pct.build(3)
+	if _, ok := strategy.(*PCTStrategy); !ok {
+		t.Errorf("expected the pct strategy, got %T", strategy)
+	}
+	if _, ok := mutator.(*EmptyMutator); !ok {
+		t.Errorf("expected no mutator by default, got %T", mutator)
+	}
+	again, _ := pct.build(3)
+	for i := 0; i < 10; i++ {
+		if a, b := strategy.GetRandomInteger(1000), again.GetRandomInteger(1000); a != b {
+			t.Fatalf("expected the seed of the run to repeat the strategy, got %d and %d", a, b)
+		}
+	}
+	if _, ok := c.benchmarks["multi"].guider.(*MultiGuider); !ok {
+		t.Errorf("expected the multi guider, got %T", c.benchmarks["multi"].guider)
+	}
+}
+
+func TestGetCompareMutatorSeed(t *testing.T) {
+	trace, events := testTrace(t)
+	for _, name := range []string{"combined", "bandit", "events", "splice"} {
+		mutated := make([]string, 0, 2)
+		for i := 0; i < 2; i++ {
+			m, err := getCompareMutator(name, eventTestConfig(), 7)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := ""
+			for j := 0; j < 5; j++ {
+				next, _ := m.Mutate(trace, events)
+				bs, _ := json.Marshal(next)
+				data += string(bs)
+			}
+			mutated = append(mutated, data)
+		}
+		if mutated[0] != mutated[1] {
+			t.Errorf("expected the seed to repeat the mutations of %s", name)
+		}
+	}
+}
+
+func TestParseExperimentConfigErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"no benchmarks": `{"runs": 2}`,
+		"unknown field": `{"benchmarks": [{"name": "a", "guider": "tlc", "mutatr": "combined"}]}`,
+		"duplicate":     `{"benchmarks": [{"name": "a", "guider": "tlc"}, {"name": "a", "guider": "line"}]}`,
+		"no guider":     `{"benchmarks": [{"name": "a"}]}`,
+		"extra seeds":   `{"runs": 1, "seeds": [1, 2], "benchmarks": [{"name": "a", "guider": "tlc"}]}`,
+	} {
+		if _, err := parseExperimentConfig([]byte(data)); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"runtime/coverage"
+
+	"github.com/zeu5/gocov"
+)
+
+// Layout of the counter data written by runtime/coverage, see
+// internal/coverage/defs.go. The layout is internal to the Go release, the
+// version in the header is checked and the data of any other version is
+// refused rather than misread.
+const (
+	counterFileVersion = 1
+	counterHeaderSize  = 32
+	counterSegmentSize = 16
+	counterFooterSize  = 16
+	counterFlavorRaw   = 1
+	counterFlavorULEB  = 2
+)
+
+var counterMagic = []byte{0x00, 0x63, 0x77, 0x6d}
+
+// coverageCounters reads the coverage counters of the packages in process.
+// The meta-data, the functions and blocks of the packages, does not change
+// while the binary runs and is parsed once, a read only decodes the counters.
+type coverageCounters struct {
+	// packages are the packages matched by their index in the meta-data
+	packages map[uint32]*gocov.Package
+	buf      bytes.Buffer
+	counters []uint32
+}
+
+func newCoverageCounters(pkgs []string) (*coverageCounters, error) {
+	cov, err := gocov.GetCoverage(gocov.CoverageConfig{MatchPkgs: pkgs})
+	if err != nil {
+		return nil, err
+	}
+	packages := make(map[uint32]*gocov.Package)
+	for _, pod := range cov.Data.PodData {
+		for idx, pkg := range pod.Packages {
+			// gocov lists the sub-packages a pattern matches but only
+			// reads the functions of the packages it names
+			if len(pkg.Funcs) > 0 {
+				packages[idx] = pkg
+			}
+		}
+	}
+	c := &coverageCounters{packages: packages}
+	// Reading the counters once makes a layout this code does not know fail
+	// before any episode runs
+	if err := c.read(func(*gocov.Package, uint32, []uint32) {}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// read calls visit with the counters of every function of the packages that
+// was executed, the counters are indexed like the units of the function
+func (c *coverageCounters) read(visit func(pkg *gocov.Package, funcIdx uint32, counters []uint32)) error {
+	c.buf.Reset()
+	if err := coverage.WriteCounters(&c.buf); err != nil {
+		return err
+	}
+	return c.decode(c.buf.Bytes(), func(pkgIdx, funcIdx uint32, counters []uint32) {
+		if pkg, ok := c.packages[pkgIdx]; ok {
+			visit(pkg, funcIdx, counters)
+		}
+	})
+}
+
+// decode calls visit with the counters of every function in the counter data
+func (c *coverageCounters) decode(data []byte, visit func(pkgIdx, funcIdx uint32, counters []uint32)) error {
+	if len(data) < counterHeaderSize+counterSegmentSize+counterFooterSize || !bytes.Equal(data[:4], counterMagic) {
+		return fmt.Errorf("invalid counter data")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:8]); version != counterFileVersion {
+		return fmt.Errorf("unsupported counter data version %d, expected %d", version, counterFileVersion)
+	}
+	footer := data[len(data)-counterFooterSize:]
+	if !bytes.Equal(footer[:4], counterMagic) {
+		return fmt.Errorf("invalid counter data footer")
+	}
+	if segments := binary.LittleEndian.Uint32(footer[8:12]); segments != 1 {
+		return fmt.Errorf("expected one segment of counter data, got %d", segments)
+	}
+	flavor := data[24]
+	bigEndian := data[25] != 0
+
+	segment := data[counterHeaderSize:]
+	numFuncs := binary.LittleEndian.Uint64(segment[0:8])
+	strTabLen := binary.LittleEndian.Uint32(segment[8:12])
+	argsLen := binary.LittleEndian.Uint32(segment[12:16])
+	// The string and args tables are padded to 4 bytes
+	off := (counterHeaderSize + counterSegmentSize + int(strTabLen) + int(argsLen) + 3) &^ 3
+	end := len(data) - counterFooterSize
+
+	next := func() (uint32, error) {
+		switch flavor {
+		case counterFlavorULEB:
+			var value uint64
+			for shift := uint(0); off < end; shift += 7 {
+				b := data[off]
+				off++
+				value |= uint64(b&0x7f) << shift
+				if b&0x80 == 0 {
+					return uint32(value), nil
+				}
+			}
+		case counterFlavorRaw:
+			if off+4 <= end {
+				off += 4
+				if bigEndian {
+					return binary.BigEndian.Uint32(data[off-4 : off]), nil
+				}
+				return binary.LittleEndian.Uint32(data[off-4 : off]), nil
+			}
+		default:
+			return 0, fmt.Errorf("unknown counter flavor %d", flavor)
+		}
+		return 0, fmt.Errorf("truncated counter data")
+	}
+
+	for f := uint64(0); f < numFuncs; f++ {
+		var header [3]uint32
+		for i := range header {
+			v, err := next()
+			if err != nil {
+				return err
+			}
+			header[i] = v
+		}
+		numCounters, pkgIdx, funcIdx := header[0], header[1], header[2]
+		c.counters = c.counters[:0]
+		for i := uint32(0); i < numCounters; i++ {
+			v, err := next()
+			if err != nil {
+				return err
+			}
+			c.counters = append(c.counters, v)
+		}
+		visit(pkgIdx, funcIdx, c.counters)
+	}
+	return nil
+}
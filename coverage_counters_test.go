@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+const counterProgram = `package main
+
+import (
+	"os"
+	"runtime/coverage"
+)
+
+func count(n int) int {
+	sum := 0
+	for i := 0; i < n; i++ {
+		sum += i
+	}
+	return sum
+}
+
+func main() {
+	count(7)
+	coverage.WriteCounters(os.Stdout)
+}
+`
+
+// TestDecodeCounters decodes the counters written by a program built with
+// the toolchain running the tests, a Go release changing the layout of the
+// counter data fails here
+func TestDecodeCounters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program with coverage")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go tool to build the program")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module counters\n\ngo 1.20\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(counterProgram), 0644); err != nil {
+		t.Fatal(err)
+	}
+	build := exec.Command(goTool, "build", "-cover", "-covermode=atomic", "-o", "counters")
+	build.Dir = dir
+	build.Env = append(os.Environ(), "GOTOOLCHAIN=local", "GOFLAGS=")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("error building the program: %s\n%s", err, out)
+	}
+	data, err := exec.Command(filepath.Join(dir, "counters")).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &coverageCounters{}
+	funcs := 0
+	loop := false
+	err = c.decode(data, func(pkgIdx, funcIdx uint32, counters []uint32) {
+		funcs += 1
+		for _, count := range counters {
+			loop = loop || count == 7
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if funcs != 2 || !loop {
+		t.Errorf("expected the counters of count and main with the 7 iterations of the loop, got %d functions", funcs)
+	}
+
+	// Any other version of the layout is refused
+	data[4] = counterFileVersion + 1
+	if err := c.decode(data, func(uint32, uint32, []uint32) {}); err == nil {
+		t.Error("expected the counter data of another version to be refused")
+	}
+}
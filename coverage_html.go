@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// guiderColors tell the guiders apart in the HTML report
+var guiderColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+type htmlCell struct {
+	Hits  string
+	Style template.CSS
+}
+
+type htmlLine struct {
+	Number     int
+	Code       string
+	Coverable  bool
+	Cells      []htmlCell
+	First      string
+	FirstStyle template.CSS
+}
+
+type htmlFile struct {
+	Name    string
+	Covered int
+	Lines   int
+	Source  []htmlLine
+}
+
+type htmlGuider struct {
+	Name  string
+	Color template.CSS
+	Lines int
+}
+
+type htmlReport struct {
+	Guiders []htmlGuider
+	Files   []htmlFile
+}
+
+// lineCoverage aggregates the line hits and the first episode that reached
+// every line over the runs of a guider
+type lineCoverage struct {
+	runs  int
+	hits  map[string]uint64
+	first map[string]int
+}
+
+func aggregateLineCoverage(records []*CoverageRecord) map[string]*lineCoverage {
+	guiders := make(map[string]*lineCoverage)
+	for _, r := range records {
+		if r.LineHits == nil {
+			continue
+		}
+		g, ok := guiders[r.Guider]
+		if !ok {
+			g = &lineCoverage{hits: make(map[string]uint64), first: make(map[string]int)}
+			guiders[r.Guider] = g
+		}
+		g.runs++
+		for l, h := range r.LineHits {
+			g.hits[l] += h
+		}
+		for _, c := range r.Contributions {
+			for _, l := range c.NewLines {
+				if first, ok := g.first[l]; !ok || c.Iteration < first {
+					g.first[l] = c.Iteration
+				}
+			}
+		}
+	}
+	return guiders
+}
+
+// heatStyle colors a line by the number of hits on a log scale, red when it
+// was never hit
+func heatStyle(hits, maxHits float64) template.CSS {
+	if hits == 0 {
+		return "background: #f8d0d0"
+	}
+	alpha := 0.15 + 0.85*math.Log1p(hits)/math.Log1p(math.Max(maxHits, 1))
+	return template.CSS(fmt.Sprintf("background: rgba(40, 160, 40, %.2f)", alpha))
+}
+
+// buildHTMLReport annotates the source files of the raft package with the
+// average hits of every line per guider and the guider that reached the line
+// in the earliest episode
+func buildHTMLReport(records []*CoverageRecord, sourceDir string) (*htmlReport, error) {
+	guiders := aggregateLineCoverage(records)
+	if len(guiders) == 0 {
+		return nil, fmt.Errorf("no line coverage was tracked, build with -cover and use --track-coverage")
+	}
+	names := make([]string, 0, len(guiders))
+	for name := range guiders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	report := &htmlReport{}
+	colors := make(map[string]string)
+	for i, name := range names {
+		colors[name] = guiderColors[i%len(guiderColors)]
+		covered := 0
+		for _, h := range guiders[name].hits {
+			if h > 0 {
+				covered++
+			}
+		}
+		report.Guiders = append(report.Guiders, htmlGuider{Name: name, Color: template.CSS(colors[name]), Lines: covered})
+	}
+
+	files := make(map[string]bool)
+	maxHits := 0.0
+	for _, g := range guiders {
+		for l, h := range g.hits {
+			file, _, _ := strings.Cut(l, ":")
+			files[file] = true
+			maxHits = math.Max(maxHits, float64(h)/float64(g.runs))
+		}
+	}
+	fileNames := make([]string, 0, len(files))
+	for f := range files {
+		fileNames = append(fileNames, f)
+	}
+	sort.Strings(fileNames)
+
+	for _, name := range fileNames {
+		source, err := readSourceLines(filepath.Join(sourceDir, name))
+		if err != nil {
+			return nil, err
+		}
+		file := htmlFile{Name: name, Lines: 0}
+		for i, code := range source {
+			key := name + ":" + strconv.Itoa(i+1)
+			line := htmlLine{Number: i + 1, Code: code}
+			first := -1
+			firstGuiders := make([]string, 0)
+			covered := false
+			for _, gName := range names {
+				g := guiders[gName]
+				h, ok := g.hits[key]
+				if !ok {
+					line.Cells = append(line.Cells, htmlCell{})
+					continue
+				}
+				line.Coverable = true
+				avg := float64(h) / float64(g.runs)
+				line.Cells = append(line.Cells, htmlCell{
+					Hits:  strconv.FormatFloat(math.Round(avg*10)/10, 'f', -1, 64),
+					Style: heatStyle(avg, maxHits),
+				})
+				if h > 0 {
+					covered = true
+				}
+				if f, ok := g.first[key]; ok {
+					switch {
+					case first == -1 || f < first:
+						first = f
+						firstGuiders = []string{gName}
+					case f == first:
+						firstGuiders = append(firstGuiders, gName)
+					}
+				}
+			}
+			if line.Coverable {
+				file.Lines++
+				if covered {
+					file.Covered++
+				}
+			}
+			if len(firstGuiders) > 0 {
+				line.First = fmt.Sprintf("%s @%d", strings.Join(firstGuiders, ", "), first)
+				if len(firstGuiders) == 1 {
+					line.FirstStyle = template.CSS("border-left: 6px solid " + colors[firstGuiders[0]])
+				} else {
+					line.FirstStyle = template.CSS("border-left: 6px solid #000")
+				}
+			}
+			file.Source = append(file.Source, line)
+		}
+		report.Files = append(report.Files, file)
+	}
+	return report, nil
+}
+
+func readSourceLines(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source file: %s", err)
+	}
+	defer file.Close()
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+var coverageHTMLTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage of the raft package</title>
+<style>
+body { font-family: sans-serif; }
+table.source { border-collapse: collapse; font-family: monospace; font-size: 12px; }
+table.source td { padding: 0 6px; white-space: pre; }
+td.num { color: #999; text-align: right; }
+td.hits { text-align: right; min-width: 4em; }
+td.first { color: #555; font-family: sans-serif; }
+span.swatch { display: inline-block; width: 1em; height: 1em; vertical-align: middle; }
+</style>
+</head>
+<body>
+<h1>Coverage of the raft package</h1>
+<p>Lines are colored by the average number of hits per run of every guider, red lines were never hit.
+The bar on the left marks the guider that reached the line in the earliest episode.</p>
+<table>
+<tr><th>Guider</th><th>Lines covered</th></tr>
+{{range .Guiders}}<tr><td><span class="swatch" style="background: {{.Color}}"></span> {{.Name}}</td><td>{{.Lines}}</td></tr>
+{{end}}</table>
+<ul>
+{{range .Files}}<li><a href="#{{.Name}}">{{.Name}}</a> {{.Covered}}/{{.Lines}}</li>
+{{end}}</ul>
+{{$guiders := .Guiders}}
+{{range .Files}}
+<h2 id="{{.Name}}">{{.Name}}</h2>
+<table class="source">
+<tr><th></th>{{range $guiders}}<th>{{.Name}}</th>{{end}}<th>first</th><th></th></tr>
+{{range .Source}}<tr style="{{.FirstStyle}}"><td class="num">{{.Number}}</td>{{range .Cells}}<td class="hits" style="{{.Style}}">{{.Hits}}</td>{{end}}<td class="first">{{.First}}</td><td>{{.Code}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// writeCoverageHTML renders the coverage records under recordPath as an
+// annotated copy of the raft sources
+func writeCoverageHTML(outPath, recordPath, sourceDir string) error {
+	records, err := readCoverageRecords(recordPath)
+	if err != nil {
+		return err
+	}
+	report, err := buildHTMLReport(records, sourceDir)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return coverageHTMLTemplate.Execute(file, report)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildHTMLReport(t *testing.T) {
+	dir := t.TempDir()
+	source := "package raft\n\nfunc a() {\n\tb()\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "raft.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	records := []*CoverageRecord{
+		{Guider: "line", Run: 0, LineHits: map[string]uint64{"raft.go:3": 4, "raft.go:4": 0}, Contributions: []Contribution{
+			{Iteration: 2, NewLines: []string{"raft.go:3"}},
+		}},
+		{Guider: "line", Run: 1, LineHits: map[string]uint64{"raft.go:3": 1, "raft.go:4": 0}},
+		{Guider: "tlc", Run: 0, LineHits: map[string]uint64{"raft.go:3": 1, "raft.go:4": 3}, Contributions: []Contribution{
+			{Iteration: 5, NewLines: []string{"raft.go:3", "raft.go:4"}},
+		}},
+		{Guider: "native", Run: 0, States: 3},
+	}
+	report, err := buildHTMLReport(records, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Guiders) != 2 || report.Guiders[0].Name != "line" || report.Guiders[0].Lines != 1 || report.Guiders[1].Lines != 2 {
+		t.Fatalf("unexpected guiders: %v", report.Guiders)
+	}
+	if len(report.Files) != 1 || report.Files[0].Lines != 2 || report.Files[0].Covered != 2 {
+		t.Fatalf("unexpected files: %v", report.Files)
+	}
+	lines := report.Files[0].Source
+	if len(lines) != 5 || lines[0].Coverable {
+		t.Fatalf("expected the package clause to not be coverable")
+	}
+	if lines[2].Cells[0].Hits != "2.5" || lines[2].First != "line @2" {
+		t.Errorf("unexpected line 3: %v %s", lines[2].Cells, lines[2].First)
+	}
+	if lines[3].First != "tlc @5" || !strings.Contains(string(lines[3].Cells[0].Style), "#f8d0d0") {
+		t.Errorf("unexpected line 4: %v %s", lines[3].Cells, lines[3].First)
+	}
+
+	if _, err := buildHTMLReport(records[3:], dir); err == nil {
+		t.Error("expected an error without line coverage")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// guiderCoverage aggregates the coverage records of the runs of one guider
+type guiderCoverage struct {
+	runs          int
+	tracked       int
+	states        int
+	contributions int
+	// reached counts the runs in which a function was covered
+	reached map[string]int
+	// first is the earliest iteration in which a function was covered
+	first map[string]int
+}
+
+func aggregateCoverageRecords(records []*CoverageRecord) map[string]*guiderCoverage {
+	guiders := make(map[string]*guiderCoverage)
+	for _, r := range records {
+		g, ok := guiders[r.Guider]
+		if !ok {
+			g = &guiderCoverage{reached: make(map[string]int), first: make(map[string]int)}
+			guiders[r.Guider] = g
+		}
+		g.runs++
+		g.states += r.States
+		g.contributions += len(r.Contributions)
+		if len(r.Functions) > 0 {
+			g.tracked++
+		}
+		for _, f := range r.Functions {
+			if f.CoveredLines > 0 {
+				g.reached[f.Key()]++
+			}
+		}
+		for _, c := range r.Contributions {
+			for _, f := range c.NewFunctions {
+				if first, ok := g.first[f]; !ok || c.Iteration < first {
+					g.first[f] = c.Iteration
+				}
+			}
+		}
+	}
+	return guiders
+}
+
+// renderCoverageReport lists the functions of the file and the guiders that
+// reached them, as the number of runs in which they were covered and the
+// earliest episode that covered them
+func renderCoverageReport(records []*CoverageRecord, file string) string {
+	guiders := aggregateCoverageRecords(records)
+	names := make([]string, 0, len(guiders))
+	for name := range guiders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	functions := make(map[string]string)
+	for _, r := range records {
+		for _, f := range r.Functions {
+			if file == "" || f.File == file {
+				functions[f.Key()] = f.Function
+			}
+		}
+	}
+	keys := make([]string, 0, len(functions))
+	for key := range functions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	b := &strings.Builder{}
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "guider\truns\tavg states\tavg contributing traces")
+	for _, name := range names {
+		g := guiders[name]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", name, g.runs, g.states/g.runs, g.contributions/g.runs)
+	}
+	w.Flush()
+	if len(keys) == 0 {
+		fmt.Fprintln(b, "\nNo line coverage was tracked, build with -cover and use --track-coverage")
+		return b.String()
+	}
+
+	fmt.Fprintf(b, "\nFunctions of %s reached (runs, first episode)\n", file)
+	w = tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "function\t%s\n", strings.Join(names, "\t"))
+	never := make([]string, 0)
+	for _, key := range keys {
+		row := []string{functions[key]}
+		reached := false
+		for _, name := range names {
+			g := guiders[name]
+			switch {
+			case g.tracked == 0:
+				row = append(row, "-")
+			case g.reached[key] == 0:
+				row = append(row, "never")
+			default:
+				reached = true
+				cell := fmt.Sprintf("%d/%d", g.reached[key], g.tracked)
+				if first, ok := g.first[key]; ok {
+					cell += fmt.Sprintf(" @%d", first)
+				}
+				row = append(row, cell)
+			}
+		}
+		if !reached {
+			never = append(never, functions[key])
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	if len(never) > 0 {
+		fmt.Fprintf(b, "\nNever reached by any guider: %s\n", strings.Join(never, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCoverageRecords(t *testing.T) {
+	recordPath := t.TempDir() + "/record"
+	guider := NewNativeStateGuider(DefaultAbstraction(), recordPath, true)
+	runEpisodes(t, guider, 5)
+	states := guider.Coverage().UniqueStates
+	guider.Reset("native")
+	runEpisodes(t, guider, 2)
+	guider.Reset("native")
+
+	records, err := readCoverageRecords(recordPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected a record of each run, got %d", len(records))
+	}
+	first := records[0]
+	if first.Run != 0 || first.Guider != "native" || first.States != states {
+		t.Errorf("unexpected record of the first run: %s %d %d", first.Guider, first.Run, first.States)
+	}
+	newStates := 0
+	for i, c := range first.Contributions {
+		if len(c.NewStates) == 0 || c.TraceHash == "" {
+			t.Errorf("expected contribution %d to have a trace and new states", i)
+		}
+		newStates += len(c.NewStates)
+	}
+	if newStates != states {
+		t.Errorf("expected the contributions to add up to %d states, got %d", states, newStates)
+	}
+	if records[1].Run != 1 || len(records[1].Contributions) == 0 {
+		t.Error("expected the contributions of the second run to start over")
+	}
+
+	quietPath := t.TempDir() + "/quiet"
+	quiet := NewNativeStateGuider(DefaultAbstraction(), quietPath, false)
+	runEpisodes(t, quiet, 2)
+	quiet.Reset("native")
+	if _, err := readCoverageRecords(quietPath); err == nil {
+		t.Error("expected no records without recording the traces")
+	}
+}
+
+func TestRenderCoverageReport(t *testing.T) {
+	functions := func(covered ...int) []FunctionCoverage {
+		names := []string{"stepLeader", "stepCandidate", "handleSnapshot"}
+		fs := make([]FunctionCoverage, len(names))
+		for i, name := range names {
+			fs[i] = FunctionCoverage{File: "raft.go", Function: name, Lines: 10, CoveredLines: covered[i]}
+		}
+		return append(fs, FunctionCoverage{File: "log.go", Function: "append", Lines: 5, CoveredLines: 5})
+	}
+	records := []*CoverageRecord{
+		{Guider: "line", Run: 0, States: 10, Functions: functions(5, 2, 0), Contributions: []Contribution{
+			{Iteration: 3, NewFunctions: []string{"raft.go:stepLeader"}},
+		}},
+		{Guider: "line", Run: 1, States: 20, Functions: functions(0, 3, 0)},
+		{Guider: "tlc", Run: 0, States: 30},
+	}
+	report := renderCoverageReport(records, "raft.go")
+	for _, expected := range []string{"1/2 @3", "2/2", "Never reached by any guider: handleSnapshot"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected %q in the report:\n%s", expected, report)
+		}
+	}
+	if strings.Contains(report, "append") {
+		t.Errorf("expected only the functions of raft.go:\n%s", report)
+	}
+	for _, line := range strings.Split(report, "\n") {
+		if strings.HasPrefix(line, "stepCandidate") && !strings.HasSuffix(strings.TrimSpace(line), "-") {
+			t.Errorf("expected no coverage of the untracked guider: %s", line)
+		}
+	}
+}
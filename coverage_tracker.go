@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/zeu5/gocov"
+)
+
+// FunctionCoverage is the line coverage of one function of the raft package
+type FunctionCoverage struct {
+	File         string
+	Function     string
+	Lines        int
+	CoveredLines int
+}
+
+func (f *FunctionCoverage) Key() string {
+	return f.File + ":" + f.Function
+}
+
+// coverageUnit identifies a coverable unit of a function
+type coverageUnit struct {
+	File     string
+	Function string
+	Unit     int
+}
+
+// coverageTracker follows the lines of the raft package covered over a run
+// and tells which lines and functions an episode reached first. The counters
+// are cumulative unless cleared, an episode hit a unit when its counter
+// changed since the last read.
+type coverageTracker struct {
+	Packages []string
+	counters *coverageCounters
+	// units are the units of every function by package and function index,
+	// lines the lines every unit spans
+	units    map[string]map[uint32][]coverageUnit
+	lines    map[coverageUnit][]string
+	last     map[coverageUnit]uint32
+	baseline bool
+	// hits counts the executions of every coverable line, zero for the lines
+	// not covered yet
+	hits      map[string]uint64
+	covered   int
+	functions map[string]*FunctionCoverage
+	failed    bool
+}
+
+func newCoverageTracker() *coverageTracker {
+	return &coverageTracker{
+		Packages:  []string{raftPackage},
+		hits:      make(map[string]uint64),
+		functions: make(map[string]*FunctionCoverage),
+	}
+}
+
+// load parses the functions of the packages and the lines of their units,
+// once
+func (c *coverageTracker) load() error {
+	if c.counters != nil {
+		return nil
+	}
+	counters, err := newCoverageCounters(c.Packages)
+	if err != nil {
+		return err
+	}
+	c.units = make(map[string]map[uint32][]coverageUnit)
+	c.lines = make(map[coverageUnit][]string)
+	for _, pkg := range counters.packages {
+		funcs := make(map[uint32][]coverageUnit, len(pkg.Funcs))
+		for idx, fn := range pkg.Funcs {
+			file := filepath.Base(fn.SrcFile)
+			units := make([]coverageUnit, len(fn.Units))
+			for i, u := range fn.Units {
+				units[i] = coverageUnit{File: file, Function: fn.Name, Unit: i}
+				for l := u.StLine; l <= u.EnLine; l++ {
+					c.lines[units[i]] = append(c.lines[units[i]], file+":"+strconv.Itoa(int(l)))
+				}
+			}
+			funcs[idx] = units
+		}
+		c.units[pkg.ImportPath] = funcs
+	}
+	c.counters = counters
+	c.addFunctions()
+	return nil
+}
+
+// addFunctions lists every function and coverable line as not covered, the
+// units of a function can share lines
+func (c *coverageTracker) addFunctions() {
+	seen := make(map[string]bool)
+	for unit, lines := range c.lines {
+		key := unit.File + ":" + unit.Function
+		f, ok := c.functions[key]
+		if !ok {
+			f = &FunctionCoverage{File: unit.File, Function: unit.Function}
+			c.functions[key] = f
+		}
+		for _, l := range lines {
+			c.hits[l] = 0
+			if !seen[l] {
+				seen[l] = true
+				f.Lines++
+			}
+		}
+	}
+}
+
+// readUnits returns the counters of the units that were executed
+func (c *coverageTracker) readUnits() (map[coverageUnit]uint32, error) {
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	counts := make(map[coverageUnit]uint32)
+	err := c.counters.read(func(pkg *gocov.Package, funcIdx uint32, counters []uint32) {
+		units := c.units[pkg.ImportPath][funcIdx]
+		for i, count := range counters {
+			if i < len(units) && count > 0 {
+				counts[units[i]] = count
+			}
+		}
+	})
+	return counts, err
+}
+
+// begin takes the counters at the start of the first episode of a run as the
+// baseline, the other guiders of a comparison have moved them since
+func (c *coverageTracker) begin() {
+	if c.baseline || c.failed {
+		return
+	}
+	counts, err := c.readUnits()
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	c.last = counts
+	c.baseline = true
+}
+
+// cleared tells the tracker that the counters were set to zero
+func (c *coverageTracker) cleared() {
+	c.last = nil
+	c.baseline = true
+}
+
+func (c *coverageTracker) fail(err error) {
+	if !c.failed {
+		fmt.Println("Error reading coverage data: " + err.Error())
+	}
+	c.failed = true
+}
+
+// update reads the counters and returns the lines and functions covered for
+// the first time
+func (c *coverageTracker) update() ([]string, []string) {
+	if c.failed {
+		return nil, nil
+	}
+	counts, err := c.readUnits()
+	if err != nil {
+		c.fail(err)
+		return nil, nil
+	}
+	newLines := make([]string, 0)
+	newFunctions := make([]string, 0)
+	for unit, count := range counts {
+		if count == c.last[unit] {
+			continue
+		}
+		// The counter started over when it went down
+		delta := count
+		if count > c.last[unit] {
+			delta = count - c.last[unit]
+		}
+		key := unit.File + ":" + unit.Function
+		f := c.functions[key]
+		covered := f.CoveredLines
+		for _, l := range c.lines[unit] {
+			if c.hits[l] == 0 {
+				newLines = append(newLines, l)
+				f.CoveredLines++
+				c.covered++
+			}
+			c.hits[l] += uint64(delta)
+		}
+		if covered == 0 && f.CoveredLines > 0 {
+			newFunctions = append(newFunctions, key)
+		}
+	}
+	c.last = counts
+	c.baseline = true
+	sort.Strings(newLines)
+	sort.Strings(newFunctions)
+	return newLines, newFunctions
+}
+
+func (c *coverageTracker) coveredLines() int {
+	return c.covered
+}
+
+func (c *coverageTracker) lineHits() map[string]uint64 {
+	hits := make(map[string]uint64, len(c.hits))
+	for l, h := range c.hits {
+		hits[l] = h
+	}
+	return hits
+}
+
+func (c *coverageTracker) functionCoverage() []FunctionCoverage {
+	functions := make([]FunctionCoverage, 0, len(c.functions))
+	for _, f := range c.functions {
+		functions = append(functions, *f)
+	}
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Key() < functions[j].Key() })
+	return functions
+}
+
+func (c *coverageTracker) reset() {
+	c.hits = make(map[string]uint64)
+	c.covered = 0
+	c.functions = make(map[string]*FunctionCoverage)
+	c.baseline = false
+	if c.counters != nil {
+		c.addFunctions()
+	}
+}
+
+// Contribution is what an interesting trace added to the coverage of a guider
+type Contribution struct {
+	Iteration int
+	// TraceHash identifies the trace, it is the trace_hash of the recorded
+	// trace file
+	TraceHash string
+	NewStates []int64
+	// NewFallbackStates are the abstract states added while TLC was
+	// unreachable
+	NewFallbackStates []int64  `json:",omitempty"`
+	NewLines          []string `json:",omitempty"`
+	NewFunctions      []string `json:",omitempty"`
+}
+
+// CoverageRecord is the coverage a guider reached in one run along with the
+// traces that contributed to it
+type CoverageRecord struct {
+	Guider string
+	Run    int
+	States int
+	// FallbackStates are the abstract states of the FallbackEpisodes checked
+	// while TLC was unreachable
+	FallbackStates   int                `json:",omitempty"`
+	FallbackEpisodes int                `json:",omitempty"`
+	Functions        []FunctionCoverage `json:",omitempty"`
+	// LineHits has the number of executions of every coverable line
+	LineHits      map[string]uint64 `json:",omitempty"`
+	Contributions []Contribution
+}
+
+const contributionsDir = "contributions"
+
+func writeCoverageRecord(recordPath string, record *CoverageRecord) error {
+	dir := path.Join(recordPath, contributionsDir)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(dir, fmt.Sprintf("%s_%d.json", record.Guider, record.Run)), data, 0644)
+}
+
+func readCoverageRecords(recordPath string) ([]*CoverageRecord, error) {
+	dir := path.Join(recordPath, contributionsDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading coverage records: %s", err)
+	}
+	records := make([]*CoverageRecord, 0)
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading coverage record: %s", err)
+		}
+		record := &CoverageRecord{}
+		if err := json.Unmarshal(data, record); err != nil {
+			return nil, fmt.Errorf("error parsing coverage record %s: %s", e.Name(), err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
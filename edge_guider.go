@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"runtime/coverage"
+
+	"github.com/zeu5/gocov"
+)
+
+// raftPackage is the package whose coverage counters guide the fuzzer
+const raftPackage = "github.com/zeu5/raft-fuzzing/raft"
+
+// EpisodeGuider is implemented by guiders that measure something over the
+// course of an episode, the fuzzer tells them when an episode starts
+type EpisodeGuider interface {
+	Guider
+	BeginEpisode()
+}
+
+func beginEpisode(g Guider) {
+	if e, ok := g.(EpisodeGuider); ok {
+		e.BeginEpisode()
+	}
+}
+
+// edgeKey identifies a coverable unit (a basic block) of a function. The Go
+// counters are per block, the guider keys on blocks and not on the edges
+// between them.
+type edgeKey struct {
+	Pkg  string
+	Func uint32
+	Unit int
+}
+
+// aflBucket maps a hit count to one of the eight buckets of AFL, as a bit
+func aflBucket(count uint32) uint8 {
+	switch {
+	case count == 0:
+		return 0
+	case count == 1:
+		return 1
+	case count == 2:
+		return 2
+	case count == 3:
+		return 4
+	case count <= 7:
+		return 8
+	case count <= 15:
+		return 16
+	case count <= 31:
+		return 32
+	case count <= 127:
+		return 64
+	}
+	return 128
+}
+
+// EdgeCoverageGuider is guided by the block coverage of the raft package, the
+// coverage counters read in process. The hit count of every block in an
+// episode is put in an AFL bucket, and a trace is interesting when a block is
+// hit a number of times in a bucket not seen before. Go only counts blocks, so
+// unlike AFL the guider does not see the edges between them.
+//
+// The binary has to be built with coverage counters, go build -cover
+// -covermode=atomic instruments the packages of the module. In atomic mode the
+// counters are cleared at the start of every episode, otherwise the counts of
+// an episode are the difference to the last read.
+type EdgeCoverageGuider struct {
+	Packages []string
+	// edges holds the buckets seen of every block as a bitmask
+	edges    map[edgeKey]uint8
+	features int
+	previous map[edgeKey]uint32
+	counters *coverageCounters
+	// err is why the counters cannot be read
+	err error
+	// probed is set once the counters were cleared, clearable when that
+	// worked
+	probed    bool
+	clearable bool
+	warned    bool
+	*TLCStateGuider
+}
+
+var _ EpisodeGuider = &EdgeCoverageGuider{}
+
+func NewEdgeCoverageGuider(tlcClient *TLCClient, recordPath string, recordTraces bool) *EdgeCoverageGuider {
+	packages := []string{raftPackage}
+	counters, err := newCoverageCounters(packages)
+	return &EdgeCoverageGuider{
+		Packages:       packages,
+		edges:          make(map[edgeKey]uint8),
+		previous:       make(map[edgeKey]uint32),
+		counters:       counters,
+		err:            err,
+		TLCStateGuider: NewTLCStateGuider(tlcClient, recordPath, recordTraces),
+	}
+}
+
+// clearCounters sets the coverage counters to zero, which only works in
+// atomic mode
+func (e *EdgeCoverageGuider) clearCounters() {
+	e.probed = true
+	e.clearable = coverage.ClearCounters() == nil
+	if e.clearable && e.tracker != nil {
+		e.tracker.cleared()
+	}
+}
+
+func (e *EdgeCoverageGuider) BeginEpisode() {
+	e.lock.Lock()
+	if e.clearable || !e.probed {
+		e.clearCounters()
+	}
+	clearable := e.clearable
+	e.lock.Unlock()
+	if !clearable {
+		e.TLCStateGuider.BeginEpisode()
+	}
+}
+
+func (e *EdgeCoverageGuider) Check(trace *List[*SchedulingChoice], events *List[*Event], states *List[*EnvState]) (int, float64) {
+	e.TLCStateGuider.Check(trace, events, states)
+	counts, err := e.readCounters()
+	if err != nil {
+		if !e.warned {
+			fmt.Println("Error reading coverage counters: " + err.Error())
+			e.warned = true
+		}
+		return 0, 0
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if !e.clearable {
+		counts = e.episodeCounts(counts)
+	}
+	curFeatures := e.features
+	newFeatures := e.observe(counts)
+	return newFeatures, float64(newFeatures) / float64(max(curFeatures, 1))
+}
+
+// readCounters returns the hit count of every block that was hit
+func (e *EdgeCoverageGuider) readCounters() (map[edgeKey]uint32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	counts := make(map[edgeKey]uint32)
+	err := e.counters.read(func(pkg *gocov.Package, funcIdx uint32, counters []uint32) {
+		for i, count := range counters {
+			if count > 0 {
+				counts[edgeKey{Pkg: pkg.ImportPath, Func: funcIdx, Unit: i}] = count
+			}
+		}
+	})
+	return counts, err
+}
+
+// episodeCounts subtracts the counts of the last read from the cumulative
+// counts
+func (e *EdgeCoverageGuider) episodeCounts(counts map[edgeKey]uint32) map[edgeKey]uint32 {
+	episode := make(map[edgeKey]uint32)
+	for key, count := range counts {
+		if count > e.previous[key] {
+			episode[key] = count - e.previous[key]
+		}
+	}
+	e.previous = counts
+	return episode
+}
+
+// observe records the buckets of the counts and returns the number of new ones
+func (e *EdgeCoverageGuider) observe(counts map[edgeKey]uint32) int {
+	newFeatures := 0
+	for key, count := range counts {
+		bucket := aflBucket(count)
+		if bucket == 0 || e.edges[key]&bucket != 0 {
+			continue
+		}
+		e.edges[key] |= bucket
+		newFeatures++
+	}
+	e.features += newFeatures
+	return newFeatures
+}
+
+func (e *EdgeCoverageGuider) Coverage() CoverageStats {
+	c := e.TLCStateGuider.Coverage()
+	e.lock.Lock()
+	c.CoveredEdges = len(e.edges)
+	c.EdgeFeatures = e.features
+	e.lock.Unlock()
+	return c
+}
+
+func (e *EdgeCoverageGuider) Reset(key string) {
+	e.lock.Lock()
+	e.edges = make(map[edgeKey]uint8)
+	e.features = 0
+	e.clearCounters()
+	e.lock.Unlock()
+	e.TLCStateGuider.Reset(key)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAFLBucket(t *testing.T) {
+	for count, bucket := range map[uint32]uint8{0: 0, 1: 1, 2: 2, 3: 4, 4: 8, 7: 8, 8: 16, 16: 32, 32: 64, 127: 64, 128: 128, 5000: 128} {
+		if b := aflBucket(count); b != bucket {
+			t.Errorf("expected bucket %d for %d hits, got %d", bucket, count, b)
+		}
+	}
+}
+
+func TestEdgeCoverageGuiderObserve(t *testing.T) {
+	g := NewEdgeCoverageGuider(nil, "", false)
+	a := edgeKey{Pkg: raftPackage, Func: 1, Unit: 0}
+	b := edgeKey{Pkg: raftPackage, Func: 1, Unit: 1}
+	if n := g.observe(map[edgeKey]uint32{a: 1, b: 5}); n != 2 {
+		t.Errorf("expected two new features, got %d", n)
+	}
+	if n := g.observe(map[edgeKey]uint32{a: 1, b: 6}); n != 0 {
+		t.Errorf("expected counts in seen buckets to be old, got %d new", n)
+	}
+	if n := g.observe(map[edgeKey]uint32{a: 2}); n != 1 {
+		t.Errorf("expected a new bucket to be a new feature, got %d", n)
+	}
+	if c := g.Coverage(); c.CoveredEdges != 2 || c.EdgeFeatures != 3 {
+		t.Errorf("unexpected coverage %v", c)
+	}
+
+	g.previous = map[edgeKey]uint32{a: 3}
+	episode := g.episodeCounts(map[edgeKey]uint32{a: 5, b: 1})
+	if episode[a] != 2 || episode[b] != 1 {
+		t.Errorf("expected the counts since the last read, got %v", episode)
+	}
+
+	g.Reset("test")
+	if c := g.Coverage(); c.CoveredEdges != 0 || c.EdgeFeatures != 0 {
+		t.Errorf("expected empty coverage after reset, got %v", c)
+	}
+}
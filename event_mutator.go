@@ -0,0 +1,361 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// The mutators in this file look at the events of the parent trace to find
+// interesting points of the execution. Every event carries the step in which
+// it occurred, and step i of an episode is driven by the i-th node choice of
+// the scheduling trace, so the events map back to the choices to change.
+
+// nodeChoiceIndices returns the positions of the node choices in the trace,
+// the i-th entry is the position of the choice made in step i
+func nodeChoiceIndices(trace *List[*SchedulingChoice]) []int {
+	indices := make([]int, 0)
+	for i, ch := range trace.Iter() {
+		if ch.Type == Node {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// findEvents returns the positions of the events matching the filter
+func findEvents(eventTrace *List[*Event], filter func(*Event) bool) []int {
+	indices := make([]int, 0)
+	for i, e := range eventTrace.Iter() {
+		if !e.Reset && filter(e) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+func isMessageEvent(t EventType, messageTypes ...string) func(*Event) bool {
+	return func(e *Event) bool {
+		if e.Name != string(t) {
+			return false
+		}
+		mType, _ := e.Params["type"].(string)
+		for _, m := range messageTypes {
+			if m == mType {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func eventParamUint(e *Event, key string) (uint64, bool) {
+	switch v := e.Params[key].(type) {
+	case uint64:
+		return v, true
+	case int:
+		return uint64(v), true
+	case float64:
+		return uint64(v), true
+	}
+	return 0, false
+}
+
+// isStepChoice is true for the choices that are keyed by the step at which
+// they apply rather than by their position in the trace
+func isStepChoice(ch *SchedulingChoice) bool {
+	return ch.Type == StopNode || ch.Type == StartNode || ch.Type == ClientRequest || ch.Type == DuplicateMessage
+}
+
+// insertAtStep copies the trace and places the choice next to the node choice
+// of its step. The fuzzer applies these choices by step, so an existing choice
+// of the same type at that step is replaced.
+func insertAtStep(trace *List[*SchedulingChoice], choice *SchedulingChoice) *List[*SchedulingChoice] {
+	newTrace := NewList[*SchedulingChoice]()
+	step := 0
+	inserted := false
+	for _, ch := range trace.Iter() {
+		if isStepChoice(ch) && ch.Type == choice.Type && ch.Step == choice.Step {
+			continue
+		}
+		if ch.Type == Node {
+			if step == choice.Step {
+				newTrace.Append(choice.Copy())
+				inserted = true
+			}
+			step += 1
+		}
+		newTrace.Append(ch.Copy())
+	}
+	if !inserted {
+		newTrace.Append(choice.Copy())
+	}
+	return newTrace
+}
+
+// CrashLeaderMutator crashes a node right after it becomes the leader and
+// optionally restarts it a few steps later
+type CrashLeaderMutator struct {
+	RestartAfter int
+	r            *rand.Rand
+}
+
+var _ Mutator = &CrashLeaderMutator{}
+
+func NewCrashLeaderMutator(restartAfter int) *CrashLeaderMutator {
+	return &CrashLeaderMutator{
+		RestartAfter: restartAfter,
+		r:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (c *CrashLeaderMutator) Seed(seed int64) {
+	c.r = rand.New(rand.NewSource(seed))
+}
+
+func (c *CrashLeaderMutator) Mutate(trace *List[*SchedulingChoice], eventTrace *List[*Event]) (*List[*SchedulingChoice], bool) {
+	leaders := findEvents(eventTrace, func(e *Event) bool { return e.Name == string(BecomeLeaderEvent) })
+	if len(leaders) == 0 {
+		return nil, false
+	}
+	e, _ := eventTrace.Get(leaders[c.r.Intn(len(leaders))])
+	crashStep := e.Step + 1
+	newTrace := insertAtStep(trace, &SchedulingChoice{
+		Type: StopNode,
+		Node: e.Node,
+		Step: crashStep,
+	})
+	if c.RestartAfter > 0 {
+		newTrace = insertAtStep(newTrace, &SchedulingChoice{
+			Type: StartNode,
+			Node: e.Node,
+			Step: crashStep + c.RestartAfter,
+		})
+	}
+	return newTrace, true
+}
+
+// DelayMessageMutator postpones the node choice that delivered a message of
+// one of the given types by Delay steps. The choices in between move one step
+// earlier.
+type DelayMessageMutator struct {
+	MessageTypes []string
+	Delay        int
+	r            *rand.Rand
+}
+
+var _ Mutator = &DelayMessageMutator{}
+
+func NewDelayMessageMutator(delay int, messageTypes ...string) *DelayMessageMutator {
+	return &DelayMessageMutator{
+		MessageTypes: messageTypes,
+		Delay:        delay,
+		r:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (d *DelayMessageMutator) Seed(seed int64) {
+	d.r = rand.New(rand.NewSource(seed))
+}
+
+// NewDelayVoteResponseMutator delays the delivery of vote responses to a
+// candidate
+func NewDelayVoteResponseMutator(delay int) *DelayMessageMutator {
+	return NewDelayMessageMutator(delay, "MsgVoteResp", "MsgPreVoteResp")
+}
+
+func (d *DelayMessageMutator) Mutate(trace *List[*SchedulingChoice], eventTrace *List[*Event]) (*List[*SchedulingChoice], bool) {
+	deliveries := findEvents(eventTrace, isMessageEvent(DeliverMessageEvent, d.MessageTypes...))
+	nodeChoices := nodeChoiceIndices(trace)
+	if len(deliveries) == 0 || d.Delay < 1 {
+		return nil, false
+	}
+	e, _ := eventTrace.Get(deliveries[d.r.Intn(len(deliveries))])
+	if e.Step >= len(nodeChoices)-1 {
+		return nil, false
+	}
+	target := min(e.Step+d.Delay, len(nodeChoices)-1)
+
+	newTrace := copyTrace(trace, defaultCopyFilter())
+	delayed, _ := newTrace.Get(nodeChoices[e.Step])
+	for step := e.Step; step < target; step++ {
+		next, _ := newTrace.Get(nodeChoices[step+1])
+		newTrace.Set(nodeChoices[step], next)
+	}
+	newTrace.Set(nodeChoices[target], delayed)
+	return newTrace, true
+}
+
+// DuplicateAppendMutator redelivers the last append on the channel to a node
+// right before the node advances its commit index
+type DuplicateAppendMutator struct {
+	r *rand.Rand
+}
+
+var _ Mutator = &DuplicateAppendMutator{}
+
+func NewDuplicateAppendMutator() *DuplicateAppendMutator {
+	return &DuplicateAppendMutator{
+		r: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (d *DuplicateAppendMutator) Seed(seed int64) {
+	d.r = rand.New(rand.NewSource(seed))
+}
+
+func (d *DuplicateAppendMutator) Mutate(trace *List[*SchedulingChoice], eventTrace *List[*Event]) (*List[*SchedulingChoice], bool) {
+	commits := findEvents(eventTrace, func(e *Event) bool { return e.Name == string(AdvanceCommitIndexEvent) })
+	isAppend := isMessageEvent(DeliverMessageEvent, "MsgApp")
+	d.r.Shuffle(len(commits), func(i, j int) { commits[i], commits[j] = commits[j], commits[i] })
+	for _, i := range commits {
+		commit, _ := eventTrace.Get(i)
+		// The duplicate is delivered after the messages of the step and before
+		// the nodes report new commits
+		for j := i - 1; j >= 0; j-- {
+			e, _ := eventTrace.Get(j)
+			if !isAppend(e) || e.Node != commit.Node {
+				continue
+			}
+			from, _ := eventParamUint(e, "from")
+			return insertAtStep(trace, &SchedulingChoice{
+				Type: DuplicateMessage,
+				From: from,
+				To:   e.Node,
+				Step: commit.Step,
+			}), true
+		}
+	}
+	return nil, false
+}
+
+// ClientRequestTimingMutator moves a client request into an election, between
+// a timeout and the next leader being elected
+type ClientRequestTimingMutator struct {
+	r *rand.Rand
+}
+
+var _ Mutator = &ClientRequestTimingMutator{}
+
+func NewClientRequestTimingMutator() *ClientRequestTimingMutator {
+	return &ClientRequestTimingMutator{
+		r: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (c *ClientRequestTimingMutator) Seed(seed int64) {
+	c.r = rand.New(rand.NewSource(seed))
+}
+
+func (c *ClientRequestTimingMutator) Mutate(trace *List[*SchedulingChoice], eventTrace *List[*Event]) (*List[*SchedulingChoice], bool) {
+	requests := make([]int, 0)
+	for i, ch := range trace.Iter() {
+		if ch.Type == ClientRequest {
+			requests = append(requests, i)
+		}
+	}
+	timeouts := findEvents(eventTrace, func(e *Event) bool { return e.Name == string(TimeoutEvent) })
+	if len(requests) == 0 || len(timeouts) == 0 {
+		return nil, false
+	}
+	i := timeouts[c.r.Intn(len(timeouts))]
+	timeout, _ := eventTrace.Get(i)
+	end := timeout.Step
+	for _, e := range eventTrace.Iter()[i+1:] {
+		end = e.Step
+		if e.Name == string(BecomeLeaderEvent) {
+			break
+		}
+	}
+	step := timeout.Step + c.r.Intn(end-timeout.Step+1)
+
+	request, _ := trace.Get(requests[c.r.Intn(len(requests))])
+	for _, j := range requests {
+		other, _ := trace.Get(j)
+		if other != request && other.Step == step {
+			return nil, false
+		}
+	}
+	moved := request.Copy()
+	moved.Step = step
+	newTrace := NewList[*SchedulingChoice]()
+	for _, ch := range trace.Iter() {
+		if ch != request {
+			newTrace.Append(ch.Copy())
+		}
+	}
+	return insertAtStep(newTrace, moved), true
+}
+
+// ChooseMutator applies one of the mutators picked at random, trying the
+// others when it cannot mutate the trace
+type ChooseMutator struct {
+	mutators []Mutator
+	last     Mutator
+	r        *rand.Rand
+}
+
+var _ Mutator = &ChooseMutator{}
+var _ NamedMutator = &ChooseMutator{}
+
+func NewChooseMutator(mutators ...Mutator) *ChooseMutator {
+	return &ChooseMutator{
+		mutators: mutators,
+		r:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Seed seeds the choice of the mutator, and every mutator with a seed drawn
+// from it
+func (c *ChooseMutator) Seed(seed int64) {
+	c.r = rand.New(rand.NewSource(seed))
+	for _, m := range c.mutators {
+		seedRandom(m, c.r.Int63())
+	}
+}
+
+func (c *ChooseMutator) Mutate(trace *List[*SchedulingChoice], eventTrace *List[*Event]) (*List[*SchedulingChoice], bool) {
+	for _, i := range c.r.Perm(len(c.mutators)) {
+		if newTrace, ok := c.mutators[i].Mutate(trace, eventTrace); ok {
+			c.last = c.mutators[i]
+			return newTrace, true
+		}
+	}
+	// No mutator applies, the failure is not counted against any of them
+	c.last = nil
+	return nil, false
+}
+
+func (c *ChooseMutator) LastMutation() string {
+	if c.last == nil {
+		return "choose"
+	}
+	return mutatorName(c.last)
+}
+
+func (c *ChooseMutator) Reset() {
+	for _, m := range c.mutators {
+		resetMutator(m)
+	}
+}
+
+func (c *ChooseMutator) Feedback(trace *List[*SchedulingChoice], newStates int) {
+	for _, m := range c.mutators {
+		mutatorFeedback(m, trace, newStates)
+	}
+}
+
+func (c *ChooseMutator) Discard() {
+	for _, m := range c.mutators {
+		discardFeedback(m)
+	}
+}
+
+// NewEventMutator combines the event aware mutators
+func NewEventMutator() *ChooseMutator {
+	return NewChooseMutator(
+		NewCrashLeaderMutator(5),
+		NewDelayVoteResponseMutator(3),
+		NewDuplicateAppendMutator(),
+		NewClientRequestTimingMutator(),
+	)
+}
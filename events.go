@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	pb "github.com/zeu5/raft-fuzzing/raft/raftpb"
+)
+
+// EventSchemaVersion is sent along with every trace so that the TLC server can
+// reject traces in a format it does not understand. Bump it whenever the
+// catalogue below changes.
+const EventSchemaVersion = "1"
+
+const EventSchemaVersionHeader = "X-Event-Schema-Version"
+
+type EventType string
+
+const (
+	DeliverMessageEvent      EventType = "DeliverMessage"
+	SendMessageEvent         EventType = "SendMessage"
+	BecomeLeaderEvent        EventType = "BecomeLeader"
+	TimeoutEvent             EventType = "Timeout"
+	ClientRequestEvent       EventType = "ClientRequest"
+	AdvanceCommitIndexEvent  EventType = "AdvanceCommitIndex"
+	AddNodeEvent             EventType = "Add"
+	RemoveNodeEvent          EventType = "Remove"
+	RandomBooleanChoiceEvent EventType = "RandomBooleanChoice"
+	RandomIntegerChoiceEvent EventType = "RandomIntegerChoice"
+)
+
+type ParamType string
+
+const (
+	IntegerParam ParamType = "integer"
+	BooleanParam ParamType = "boolean"
+	StringParam  ParamType = "string"
+	ArrayParam   ParamType = "array"
+)
+
+type EventSpec struct {
+	Type        EventType
+	Description string
+	Params      map[string]ParamType
+}
+
+var messageParams = map[string]ParamType{
+	"type":     StringParam,
+	"term":     IntegerParam,
+	"from":     IntegerParam,
+	"to":       IntegerParam,
+	"log_term": IntegerParam,
+	"entries":  ArrayParam,
+	"index":    IntegerParam,
+	"commit":   IntegerParam,
+	"vote":     IntegerParam,
+	"reject":   BooleanParam,
+}
+
+var EventCatalogue = map[EventType]EventSpec{
+	DeliverMessageEvent: {
+		Type:        DeliverMessageEvent,
+		Description: "A message is delivered to its recipient",
+		Params:      messageParams,
+	},
+	SendMessageEvent: {
+		Type:        SendMessageEvent,
+		Description: "A node sends a message",
+		Params:      messageParams,
+	},
+	BecomeLeaderEvent: {
+		Type:        BecomeLeaderEvent,
+		Description: "A node becomes the leader",
+		Params:      map[string]ParamType{"node": IntegerParam},
+	},
+	TimeoutEvent: {
+		Type:        TimeoutEvent,
+		Description: "A node times out and starts an election",
+		Params:      map[string]ParamType{"node": IntegerParam},
+	},
+	ClientRequestEvent: {
+		Type:        ClientRequestEvent,
+		Description: "The leader receives a client request, request 0 is the empty entry of a new leader",
+		Params:      map[string]ParamType{"request": IntegerParam, "leader": IntegerParam},
+	},
+	AdvanceCommitIndexEvent: {
+		Type:        AdvanceCommitIndexEvent,
+		Description: "A node has new committed entries",
+		Params:      map[string]ParamType{"i": IntegerParam},
+	},
+	AddNodeEvent: {
+		Type:        AddNodeEvent,
+		Description: "A stopped node is restarted",
+		Params:      map[string]ParamType{"i": IntegerParam},
+	},
+	RemoveNodeEvent: {
+		Type:        RemoveNodeEvent,
+		Description: "A node is stopped",
+		Params:      map[string]ParamType{"i": IntegerParam},
+	},
+	RandomBooleanChoiceEvent: {
+		Type:        RandomBooleanChoiceEvent,
+		Description: "A random boolean is drawn",
+		Params:      map[string]ParamType{"choice": BooleanParam},
+	},
+	RandomIntegerChoiceEvent: {
+		Type:        RandomIntegerChoiceEvent,
+		Description: "A random integer is drawn",
+		Params:      map[string]ParamType{"choice": IntegerParam},
+	},
+}
+
+func newMessageEvent(t EventType, node uint64, message pb.Message) *Event {
+	return &Event{
+		Name: string(t),
+		Node: node,
+		Params: map[string]interface{}{
+			"type":     message.Type.String(),
+			"term":     message.Term,
+			"from":     message.From,
+			"to":       message.To,
+			"log_term": message.LogTerm,
+			"entries":  message.Entries,
+			"index":    message.Index,
+			"commit":   message.Commit,
+			"vote":     message.Vote,
+			"reject":   message.Reject,
+		},
+	}
+}
+
+func NewDeliverMessageEvent(message pb.Message) *Event {
+	return newMessageEvent(DeliverMessageEvent, message.To, message)
+}
+
+func NewSendMessageEvent(message pb.Message) *Event {
+	return newMessageEvent(SendMessageEvent, message.From, message)
+}
+
+func NewBecomeLeaderEvent(node uint64) *Event {
+	return &Event{
+		Name:   string(BecomeLeaderEvent),
+		Node:   node,
+		Params: map[string]interface{}{"node": node},
+	}
+}
+
+func NewTimeoutEvent(node uint64) *Event {
+	return &Event{
+		Name:   string(TimeoutEvent),
+		Node:   node,
+		Params: map[string]interface{}{"node": node},
+	}
+}
+
+func NewClientRequestEvent(leader uint64, request int) *Event {
+	return &Event{
+		Name: string(ClientRequestEvent),
+		Node: leader,
+		Params: map[string]interface{}{
+			"request": request,
+			"leader":  leader,
+		},
+	}
+}
+
+func NewAdvanceCommitIndexEvent(node uint64) *Event {
+	return &Event{
+		Name:   string(AdvanceCommitIndexEvent),
+		Node:   node,
+		Params: map[string]interface{}{"i": int(node)},
+	}
+}
+
+func NewAddNodeEvent(node uint64) *Event {
+	return &Event{
+		Name:   string(AddNodeEvent),
+		Node:   node,
+		Params: map[string]interface{}{"i": int(node)},
+	}
+}
+
+func NewRemoveNodeEvent(node uint64) *Event {
+	return &Event{
+		Name:   string(RemoveNodeEvent),
+		Node:   node,
+		Params: map[string]interface{}{"i": int(node)},
+	}
+}
+
+func NewRandomBooleanChoiceEvent(choice bool) *Event {
+	return &Event{
+		Name:   string(RandomBooleanChoiceEvent),
+		Params: map[string]interface{}{"choice": choice},
+	}
+}
+
+func NewRandomIntegerChoiceEvent(choice int) *Event {
+	return &Event{
+		Name:   string(RandomIntegerChoiceEvent),
+		Params: map[string]interface{}{"choice": choice},
+	}
+}
+
+func checkParamType(value interface{}, t ParamType) bool {
+	if value == nil {
+		return t == ArrayParam
+	}
+	v := reflect.ValueOf(value)
+	switch t {
+	case IntegerParam:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		case reflect.Float32, reflect.Float64:
+			return v.Float() == float64(int64(v.Float()))
+		}
+	case BooleanParam:
+		return v.Kind() == reflect.Bool
+	case StringParam:
+		return v.Kind() == reflect.String
+	case ArrayParam:
+		return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+	}
+	return false
+}
+
+// ValidateEvent checks the event against the catalogue
+func ValidateEvent(e *Event) error {
+	if e.Reset {
+		return nil
+	}
+	spec, ok := EventCatalogue[EventType(e.Name)]
+	if !ok {
+		return fmt.Errorf("unknown event: %s", e.Name)
+	}
+	for name, t := range spec.Params {
+		value, ok := e.Params[name]
+		if !ok {
+			return fmt.Errorf("event %s: missing param %s", e.Name, name)
+		}
+		if !checkParamType(value, t) {
+			return fmt.Errorf("event %s: param %s is not of type %s", e.Name, name, t)
+		}
+	}
+	for name := range e.Params {
+		if _, ok := spec.Params[name]; !ok {
+			return fmt.Errorf("event %s: unexpected param %s", e.Name, name)
+		}
+	}
+	return nil
+}
+
+func ValidateEventTrace(trace *List[*Event]) error {
+	for i, e := range trace.Iter() {
+		if err := ValidateEvent(e); err != nil {
+			return fmt.Errorf("event %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// EventJSONSchema describes the body of a request to the TLC server
+func EventJSONSchema() map[string]interface{} {
+	types := make([]string, 0, len(EventCatalogue))
+	for t := range EventCatalogue {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+
+	variants := make([]interface{}, 0, len(types)+1)
+	for _, t := range types {
+		spec := EventCatalogue[EventType(t)]
+		params := make(map[string]interface{})
+		required := make([]string, 0, len(spec.Params))
+		for name, pt := range spec.Params {
+			params[name] = map[string]interface{}{"type": string(pt)}
+			if pt == ArrayParam {
+				params[name] = map[string]interface{}{"type": []string{"array", "null"}}
+			}
+			required = append(required, name)
+		}
+		sort.Strings(required)
+		variants = append(variants, map[string]interface{}{
+			"description": spec.Description,
+			"type":        "object",
+			"properties": map[string]interface{}{
+				"Name": map[string]interface{}{"const": t},
+				"Params": map[string]interface{}{
+					"type":                 "object",
+					"properties":           params,
+					"required":             required,
+					"additionalProperties": false,
+				},
+				"Reset": map[string]interface{}{"const": false},
+			},
+			"required": []string{"Name", "Params"},
+		})
+	}
+	variants = append(variants, map[string]interface{}{
+		"description": "Marks the end of a trace, the model is reset to its initial state",
+		"type":        "object",
+		"properties": map[string]interface{}{
+			"Reset": map[string]interface{}{"const": true},
+		},
+		"required": []string{"Reset"},
+	})
+
+	return map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"$id":         "https://github.com/zeu5/raft-fuzzing/event_schema.json",
+		"title":       "Event trace",
+		"description": fmt.Sprintf("Body of a request to the /execute endpoint of the TLC server, version %s (sent in the %s header)", EventSchemaVersion, EventSchemaVersionHeader),
+		"version":     EventSchemaVersion,
+		"type":        "array",
+		"items": map[string]interface{}{
+			"oneOf": variants,
+		},
+	}
+}
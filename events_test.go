@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateEvent(t *testing.T) {
+	valid := []*Event{
+		NewTimeoutEvent(1),
+		NewAddNodeEvent(2),
+		NewRandomBooleanChoiceEvent(true),
+		{Reset: true},
+	}
+	for _, e := range valid {
+		if err := ValidateEvent(e); err != nil {
+			t.Errorf("expected %s to be valid: %s", e.Name, err)
+		}
+	}
+
+	invalid := []*Event{
+		{Name: "Unknown", Params: map[string]interface{}{}},
+		{Name: "Timeout", Params: map[string]interface{}{}},
+		{Name: "Timeout", Params: map[string]interface{}{"node": "1"}},
+		{Name: "Timeout", Params: map[string]interface{}{"node": 1.5}},
+		{Name: "Timeout", Params: map[string]interface{}{"node": 1, "term": 2}},
+	}
+	for _, e := range invalid {
+		if err := ValidateEvent(e); err == nil {
+			t.Errorf("expected %s %v to be invalid", e.Name, e.Params)
+		}
+	}
+}
+
+func TestValidateRecordedTraces(t *testing.T) {
+	f := NewFuzzer(testFuzzerConfig(NewNativeStateGuider(DefaultAbstraction(), "", false), &EmptyMutator{}))
+	for i := 0; i < 10; i++ {
+		_, events, _ := f.RunIteration("validate", nil)
+		if err := ValidateEventTrace(events); err != nil {
+			t.Fatal(err)
+		}
+		// Traces read back by the TLC server have numbers decoded as floats
+		data, _ := json.Marshal(events)
+		decoded := NewList[*Event]()
+		if err := json.Unmarshal(data, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateEventTrace(decoded); err != nil {
+			t.Fatalf("decoded trace: %s", err)
+		}
+	}
+}
+
+func TestTLCClientRejectsInvalidTrace(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	events := testEventTrace()
+	events.Append(&Event{Name: "Timeout", Params: map[string]interface{}{}})
+	if _, err := tlc.Client().SendTrace(events); err == nil {
+		t.Error("expected an invalid trace to be rejected")
+	}
+	if tlc.Requests() != 0 {
+		t.Errorf("expected no request to be sent, got %d", tlc.Requests())
+	}
+}
+
+func TestTLCSchemaVersionMismatch(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	data, _ := json.Marshal(testEventTrace().Iter())
+	req, _ := http.NewRequest(http.MethodPost, "http://"+tlc.Addr()+"/execute", bytes.NewBuffer(data))
+	req.Header.Set(EventSchemaVersionHeader, "0")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, res.StatusCode)
+	}
+
+	tlc.lock.Lock()
+	tlc.version = "0"
+	tlc.lock.Unlock()
+	requests := tlc.Requests()
+	if _, err := tlc.Client().SendTrace(testEventTrace()); err == nil {
+		t.Error("expected a version mismatch to be reported")
+	}
+	if tlc.Requests()-requests != 1 {
+		t.Errorf("expected a rejected request not to be retried, got %d requests", tlc.Requests()-requests)
+	}
+}
+
+func TestEventSchemaUpToDate(t *testing.T) {
+	data, err := os.ReadFile("event_schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := json.MarshalIndent(EventJSONSchema(), "", "\t")
+	if strings.TrimSpace(string(data)) != string(expected) {
+		t.Error("event_schema.json is out of date, regenerate it with the schema command")
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	pb "github.com/zeu5/raft-fuzzing/raft/raftpb"
+)
+
+// Explorer enumerates all the scheduling choice sequences up to a depth
+// (optionally bounding the number of deviations from a round robin schedule)
+// and counts the distinct states reached. Sequences leading to an already
+// visited state are not extended further.
+type Explorer struct {
+	config     *FuzzerConfig
+	depth      int
+	delayBound int
+	savePath   string
+
+	fuzzer   *Fuzzer
+	alphabet []*SchedulingChoice
+	states   map[string]bool
+}
+
+type explorePrefix struct {
+	trace    *List[*SchedulingChoice]
+	delays   int
+	requests int
+}
+
+type ExploreResult struct {
+	Depth       int
+	DelayBound  int
+	Executions  int
+	TotalStates int
+	NewStates   []int
+	BuggyStates int
+	Frontier    int
+}
+
+func NewExplorer(config *FuzzerConfig, depth, delayBound int, savePath string) *Explorer {
+	e := &Explorer{
+		config:     config,
+		depth:      depth,
+		delayBound: delayBound,
+		savePath:   savePath,
+		fuzzer:     NewFuzzer(config),
+		alphabet:   make([]*SchedulingChoice, 0),
+		states:     make(map[string]bool),
+	}
+	for _, from := range e.fuzzer.nodes[1:] {
+		for _, to := range e.fuzzer.nodes[1:] {
+			if from == to {
+				continue
+			}
+			e.alphabet = append(e.alphabet, &SchedulingChoice{
+				Type:        Node,
+				From:        from,
+				To:          to,
+				MaxMessages: config.MaxMessages,
+			})
+		}
+	}
+	return e
+}
+
+// extend returns the prefixes obtained by adding one step to p. A client
+// request takes a step of its own where no message is delivered
+func (e *Explorer) extend(p *explorePrefix, step int) []*explorePrefix {
+	result := make([]*explorePrefix, 0)
+	for i, choice := range e.alphabet {
+		delays := p.delays
+		if i != step%len(e.alphabet) {
+			delays += 1
+		}
+		if e.delayBound >= 0 && delays > e.delayBound {
+			continue
+		}
+		trace := copyTrace(p.trace, defaultCopyFilter())
+		trace.Append(choice.Copy())
+		result = append(result, &explorePrefix{trace: trace, delays: delays, requests: p.requests})
+	}
+	if p.requests < e.config.NumberRequests {
+		trace := copyTrace(p.trace, defaultCopyFilter())
+		trace.Append(&SchedulingChoice{Type: Node})
+		trace.Append(&SchedulingChoice{
+			Type:    ClientRequest,
+			Step:    step,
+			Request: p.requests + 1,
+		})
+		result = append(result, &explorePrefix{trace: trace, delays: p.delays, requests: p.requests + 1})
+	}
+	return result
+}
+
+func (e *Explorer) Explore() *ExploreResult {
+	result := &ExploreResult{
+		Depth:      e.depth,
+		DelayBound: e.delayBound,
+		NewStates:  make([]int, 0),
+	}
+	root := &explorePrefix{trace: NewList[*SchedulingChoice]()}
+	e.config.Steps = 0
+	e.fuzzer.RunIteration("explore_0", root.trace)
+	e.states[e.stateHash(root.requests)] = true
+
+	frontier := []*explorePrefix{root}
+	for d := 1; d <= e.depth; d++ {
+		e.config.Steps = d
+		next := make([]*explorePrefix, 0)
+		for _, p := range frontier {
+			for _, np := range e.extend(p, d-1) {
+				result.Executions += 1
+				iteration := fmt.Sprintf("explore_%d", result.Executions)
+				e.fuzzer.RunIteration(iteration, np.trace)
+				hash := e.stateHash(np.requests)
+				if _, ok := e.states[hash]; ok {
+					continue
+				}
+				e.states[hash] = true
+				if e.fuzzer.stats.IsBuggy(iteration) {
+					result.BuggyStates += 1
+				}
+				next = append(next, np)
+			}
+		}
+		result.NewStates = append(result.NewStates, len(next))
+		fmt.Printf("Depth %d: %d new states, %d total states\n", d, len(next), len(e.states))
+		frontier = next
+		if len(frontier) == 0 {
+			break
+		}
+	}
+	result.TotalStates = len(e.states)
+	result.Frontier = len(frontier)
+	e.record(result)
+	return result
+}
+
+type exploreNodeState struct {
+	Active    bool
+	State     string
+	Term      uint64
+	Vote      uint64
+	Commit    uint64
+	Lead      uint64
+	Applied   uint64
+	LastIndex uint64
+	LogTerms  []uint64
+	Progress  map[uint64][2]uint64
+	// The timers decide when the node times out or sends heartbeats
+	ElectionElapsed  int
+	HeartbeatElapsed int
+	ElectionTimeout  int
+}
+
+// stateHash hashes the raft status, timers and log of every node along with
+// the contents of the message queues and the number of client requests
+// already made, which decides the requests the prefix can still make. The
+// same state reached at different depths hashes the same.
+func (e *Explorer) stateHash(requests int) string {
+	env := e.fuzzer.raftEnvironment
+	nodes := make(map[uint64]exploreNodeState)
+	for id, storage := range env.storages {
+		s := exploreNodeState{}
+		if node, ok := env.nodes[id]; ok {
+			status := node.Status()
+			s.Active = true
+			s.State = status.RaftState.String()
+			s.Term = status.Term
+			s.Vote = status.Vote
+			s.Commit = status.Commit
+			s.Lead = status.Lead
+			s.Applied = status.Applied
+			s.Progress = make(map[uint64][2]uint64)
+			for pid, pr := range status.Progress {
+				s.Progress[pid] = [2]uint64{pr.Match, pr.Next}
+			}
+			s.ElectionElapsed, s.HeartbeatElapsed, s.ElectionTimeout = node.Timers()
+		}
+		s.LastIndex, _ = storage.LastIndex()
+		s.LogTerms = make([]uint64, 0)
+		for i := uint64(1); i <= s.LastIndex; i++ {
+			term, _ := storage.Term(i)
+			s.LogTerms = append(s.LogTerms, term)
+		}
+		nodes[id] = s
+	}
+	queueKeys := make([]string, 0)
+	for key, q := range e.fuzzer.messageQueues {
+		if q.Size() > 0 {
+			queueKeys = append(queueKeys, key)
+		}
+	}
+	sort.Strings(queueKeys)
+	queues := make(map[string][]pb.Message)
+	for _, key := range queueKeys {
+		queues[key] = e.fuzzer.messageQueues[key].q
+	}
+	bs, _ := json.Marshal(map[string]interface{}{
+		"nodes":    nodes,
+		"queues":   queues,
+		"requests": requests,
+	})
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:])
+}
+
+func (e *Explorer) record(result *ExploreResult) {
+	if e.savePath == "" {
+		return
+	}
+	if _, err := os.Stat(e.savePath); err != nil {
+		os.MkdirAll(e.savePath, 0777)
+	}
+	bs, err := json.MarshalIndent(result, "", "\t")
+	if err != nil {
+		return
+	}
+	os.WriteFile(path.Join(e.savePath, "explore.json"), bs, 0644)
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func testExplorer(numberRequests int) *Explorer {
+	return NewExplorer(&FuzzerConfig{
+		Strategy: NewRandomStrategy(),
+		RaftEnvironmentConfig: RaftEnvironmentConfig{
+			Replicas:      3,
+			ElectionTick:  20,
+			HeartbeatTick: 4,
+			TicksPerStep:  3,
+			Seed:          1,
+		},
+		NumberRequests: numberRequests,
+		MaxMessages:    5,
+	}, 1, -1, "")
+}
+
+func TestExploreRequests(t *testing.T) {
+	// Without a leader the request is dropped, the prefix with the request
+	// reaches the same raft state as the ones delivering the empty queues
+	// but has one request less to make
+	without := testExplorer(0).Explore()
+	with := testExplorer(1).Explore()
+	if with.NewStates[0] != without.NewStates[0]+1 {
+		t.Errorf("expected the prefix with the request to survive, got %d and %d new states", with.NewStates[0], without.NewStates[0])
+	}
+}
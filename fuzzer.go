@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"math/rand"
 	"strconv"
 	"time"
 
@@ -14,23 +13,42 @@ type Fuzzer struct {
 	nodes              []uint64
 	config             *FuzzerConfig
 	mutatedTracesQueue *Queue[*List[*SchedulingChoice]]
-	rand               *rand.Rand
 	raftEnvironment    *RaftEnvironment
+	validator          *TraceValidator
+	// mutationOrigins maps the repaired traces in the queue to the traces the
+	// mutator returned, the mutator learns from the traces it knows
+	mutationOrigins map[*List[*SchedulingChoice]]*List[*SchedulingChoice]
+
+	stats *FuzzerStats
+	// violation is the reason the checker gave for the last iteration, empty
+	// when it passed
+	violation string
+	// elapsed is the wall-clock time at the end of every iteration of Run
+	elapsed []time.Duration
+}
 
-	stats map[string]interface{}
+// BugRecord is a failure of the checker in an iteration of Run
+type BugRecord struct {
+	Iteration int
+	// Elapsed is the wall-clock time since the start of Run
+	Elapsed   time.Duration
+	Signature string
 }
 
 type traceCtx struct {
 	trace          *List[*SchedulingChoice]
 	mimicTrace     *List[*SchedulingChoice]
 	eventTrace     *List[*Event]
+	stateTrace     *List[*EnvState]
 	nodeChoices    *Queue[*SchedulingChoice]
 	booleanChoices *Queue[bool]
 	integerChoices *Queue[int]
 	crashPoints    map[int]uint64
 	startPoints    map[int]uint64
 	clientRequests map[int]int
-	rand           *rand.Rand
+	duplicates     map[int][2]uint64
+	strategy       Strategy
+	step           int
 
 	Error  error
 	fuzzer *Fuzzer
@@ -48,6 +66,11 @@ func (t *traceCtx) IsError() bool {
 	return t.Error != nil
 }
 
+func (t *traceCtx) addEvent(e *Event) {
+	e.Step = t.step
+	t.eventTrace.Append(e)
+}
+
 func (t *traceCtx) GetNextNodeChoice() (uint64, uint64, int) {
 	var fromChoice uint64
 	var toChoice uint64
@@ -58,11 +81,12 @@ func (t *traceCtx) GetNextNodeChoice() (uint64, uint64, int) {
 		toChoice = c.To
 		maxMessages = c.MaxMessages
 	} else {
-		i := t.rand.Intn(len(t.fuzzer.nodes))
-		j := t.rand.Intn(len(t.fuzzer.nodes))
-		fromChoice = t.fuzzer.nodes[i]
-		toChoice = t.fuzzer.nodes[j]
-		maxMessages = t.rand.Intn(t.fuzzer.config.MaxMessages)
+		fromChoice, toChoice, maxMessages = t.strategy.GetNextNodeChoice(&StrategyContext{
+			Step:        t.step,
+			Replicas:    t.fuzzer.nodes[1:],
+			MaxMessages: t.fuzzer.config.MaxMessages,
+			fuzzer:      t.fuzzer,
+		})
 	}
 	t.trace.Append(&SchedulingChoice{
 		Type:        Node,
@@ -78,14 +102,9 @@ func (t *traceCtx) GetRandomBoolean() (choice bool) {
 	if t.booleanChoices.Size() > 0 {
 		choice, _ = t.booleanChoices.Pop()
 	} else {
-		choice = t.rand.Intn(2) == 0
+		choice = t.strategy.GetRandomBoolean()
 	}
-	t.eventTrace.Append(&Event{
-		Name: "RandomBooleanChoice",
-		Params: map[string]interface{}{
-			"choice": choice,
-		},
-	})
+	t.addEvent(NewRandomBooleanChoiceEvent(choice))
 	t.trace.Append(&SchedulingChoice{
 		Type:          RandomBoolean,
 		BooleanChoice: choice,
@@ -97,14 +116,9 @@ func (t *traceCtx) GetRandomInteger(max int) (choice int) {
 	if t.integerChoices.Size() > 0 {
 		choice, _ = t.integerChoices.Pop()
 	} else {
-		choice = t.rand.Intn(max)
+		choice = t.strategy.GetRandomInteger(max)
 	}
-	t.eventTrace.Append(&Event{
-		Name: "RandomIntegerChoice",
-		Params: map[string]interface{}{
-			"choice": choice,
-		},
-	})
+	t.addEvent(NewRandomIntegerChoiceEvent(choice))
 	t.trace.Append(&SchedulingChoice{
 		Type:          RandomInteger,
 		IntegerChoice: choice,
@@ -115,13 +129,7 @@ func (t *traceCtx) GetRandomInteger(max int) (choice int) {
 func (t *traceCtx) CanCrash(step int) (uint64, bool) {
 	node, ok := t.crashPoints[step]
 	if ok {
-		t.eventTrace.Append(&Event{
-			Name: "Remove",
-			Node: node,
-			Params: map[string]interface{}{
-				"i": int(node),
-			},
-		})
+		t.addEvent(NewRemoveNodeEvent(node))
 		t.trace.Append(&SchedulingChoice{
 			Type: StopNode,
 			Node: node,
@@ -134,13 +142,7 @@ func (t *traceCtx) CanCrash(step int) (uint64, bool) {
 func (t *traceCtx) CanStart(step int) (uint64, bool) {
 	node, ok := t.startPoints[step]
 	if ok {
-		t.eventTrace.Append(&Event{
-			Name: "Add",
-			Node: node,
-			Params: map[string]interface{}{
-				"i": int(node),
-			},
-		})
+		t.addEvent(NewAddNodeEvent(node))
 		t.trace.Append(&SchedulingChoice{
 			Type: StartNode,
 			Node: node,
@@ -150,12 +152,26 @@ func (t *traceCtx) CanStart(step int) (uint64, bool) {
 	return node, ok
 }
 
+func (t *traceCtx) IsDuplicate(step int) (uint64, uint64, bool) {
+	channel, ok := t.duplicates[step]
+	if ok {
+		t.trace.Append(&SchedulingChoice{
+			Type: DuplicateMessage,
+			From: channel[0],
+			To:   channel[1],
+			Step: step,
+		})
+	}
+	return channel[0], channel[1], ok
+}
+
 func (t *traceCtx) IsClientRequest(step int) (int, bool) {
 	req, ok := t.clientRequests[step]
 	if ok {
 		t.trace.Append(&SchedulingChoice{
 			Type:    ClientRequest,
 			Request: req,
+			Step:    step,
 		})
 	}
 	return req, ok
@@ -175,17 +191,42 @@ type FuzzerConfig struct {
 	CrashQuota            int
 	MaxMessages           int
 	ReseedFrequency       int
+	// CheckerName names the checker in the statistics
+	CheckerName string
+	// Observer is told the progress of Run after every iteration
+	Observer FuzzerObserver
+}
+
+// FuzzerProgress is the state of Run after an iteration
+type FuzzerProgress struct {
+	Iteration  int
+	Iterations int
+	Elapsed    time.Duration
+	Coverage   CoverageStats
+	// QueueSize is the number of mutated traces waiting to be run
+	QueueSize int
+	// Stats are updated as the run goes on
+	Stats *FuzzerStats
+}
+
+// FuzzerObserver follows the progress of Run, Observe is called on the
+// goroutine of Run
+type FuzzerObserver interface {
+	Observe(FuzzerProgress)
 }
 
 func NewFuzzer(config *FuzzerConfig) *Fuzzer {
+	if config.Strategy == nil {
+		config.Strategy = NewRandomStrategy()
+	}
 	f := &Fuzzer{
 		config:             config,
 		nodes:              make([]uint64, 0),
 		messageQueues:      make(map[string]*Queue[pb.Message]),
 		mutatedTracesQueue: NewQueue[*List[*SchedulingChoice]](),
-		rand:               rand.New(rand.NewSource(time.Now().UnixNano())),
 		raftEnvironment:    NewRaftEnvironment(config.RaftEnvironmentConfig),
-		stats:              make(map[string]interface{}),
+		mutationOrigins:    make(map[*List[*SchedulingChoice]]*List[*SchedulingChoice]),
+		stats:              NewFuzzerStats(),
 	}
 	for i := 0; i <= f.config.RaftEnvironmentConfig.Replicas; i++ {
 		f.nodes = append(f.nodes, uint64(i))
@@ -194,14 +235,25 @@ func NewFuzzer(config *FuzzerConfig) *Fuzzer {
 			f.messageQueues[key] = NewQueue[pb.Message]()
 		}
 	}
-	f.stats["random_executions"] = 0
-	f.stats["mutated_executions"] = 0
-	f.stats["execution_errors"] = make(map[string]bool, 0)
-	f.stats["error_executions"] = make(map[string][]string)
-	f.stats["buggy_executions"] = make(map[string]bool, 0)
+	f.validator = NewTraceValidator(NewTraceLimits(config), f.nodes[1:])
+	seedRandom(f.validator, config.RaftEnvironmentConfig.Seed)
+	if config.Guider != nil {
+		setEnvironment(config.Guider, TraceEnvironment{
+			Raft:        config.RaftEnvironmentConfig,
+			Steps:       config.Steps,
+			MaxMessages: config.MaxMessages,
+		})
+	}
 	return f
 }
 
+func (f *Fuzzer) checkerName() string {
+	if f.config.CheckerName != "" {
+		return f.config.CheckerName
+	}
+	return "checker"
+}
+
 func (f *Fuzzer) Schedule(from uint64, to uint64, maxMessages int) []pb.Message {
 	key := fmt.Sprintf("%d_%d", from, to)
 	queue, ok := f.messageQueues[key]
@@ -219,54 +271,28 @@ func (f *Fuzzer) Schedule(from uint64, to uint64, maxMessages int) []pb.Message
 	return messages
 }
 
-func recordReceive(message pb.Message, eventTrace *List[*Event]) {
-	eventTrace.Append(&Event{
-		Name: "DeliverMessage",
-		Node: message.To,
-		Params: map[string]interface{}{
-			"type":     message.Type.String(),
-			"term":     message.Term,
-			"from":     message.From,
-			"to":       message.To,
-			"log_term": message.LogTerm,
-			"entries":  message.Entries,
-			"index":    message.Index,
-			"commit":   message.Commit,
-			"vote":     message.Vote,
-			"reject":   message.Reject,
-		},
-	})
+func recordReceive(message pb.Message, tCtx *traceCtx) {
+	tCtx.addEvent(NewDeliverMessageEvent(message))
 }
 
-func recordSend(message pb.Message, eventTrace *List[*Event]) {
-	eventTrace.Append(&Event{
-		Name: "SendMessage",
-		Node: message.From,
-		Params: map[string]interface{}{
-			"type":     message.Type.String(),
-			"term":     message.Term,
-			"from":     message.From,
-			"to":       message.To,
-			"log_term": message.LogTerm,
-			"entries":  message.Entries,
-			"index":    message.Index,
-			"commit":   message.Commit,
-			"vote":     message.Vote,
-			"reject":   message.Reject,
-		},
-	})
+func recordSend(message pb.Message, tCtx *traceCtx) {
+	tCtx.addEvent(NewSendMessageEvent(message))
 }
 
 func (f *Fuzzer) seed() {
 	f.mutatedTracesQueue.Reset()
+	discardFeedback(f.config.Mutator)
+	f.mutationOrigins = make(map[*List[*SchedulingChoice]]*List[*SchedulingChoice])
 	for i := 0; i < f.config.SeedPopulationSize; i++ {
-		trace, _ := f.RunIteration(fmt.Sprintf("pop_%d", i), nil)
+		trace, _, _ := f.RunIteration(fmt.Sprintf("pop_%d", i), nil)
 		f.mutatedTracesQueue.Push(copyTrace(trace, defaultCopyFilter()))
 	}
 }
 
 func (f *Fuzzer) Run() []CoverageStats {
 	coverages := make([]CoverageStats, 0)
+	f.elapsed = make([]time.Duration, 0, f.config.Iterations)
+	start := time.Now()
 	for i := 0; i < f.config.Iterations; i++ {
 		if i%f.config.ReseedFrequency == 0 {
 			f.seed()
@@ -274,40 +300,92 @@ func (f *Fuzzer) Run() []CoverageStats {
 		fmt.Printf("\rRunning iteration: %d/%d", i+1, f.config.Iterations)
 		var mimic *List[*SchedulingChoice] = nil
 		if f.mutatedTracesQueue.Size() > 0 {
-			f.stats["mutated_executions"] = f.stats["mutated_executions"].(int) + 1
 			mimic, _ = f.mutatedTracesQueue.Pop()
-		} else {
-			f.stats["random_executions"] = f.stats["random_executions"].(int) + 1
 		}
-		trace, eventTrace := f.RunIteration(fmt.Sprintf("fuzz_%d", i), mimic)
-		if numNewStates, _ := f.config.Guider.Check(trace, eventTrace); numNewStates > 0 {
+		iteration := fmt.Sprintf("fuzz_%d", i)
+		trace, eventTrace, stateTrace := f.RunIteration(iteration, mimic)
+		f.stats.recordExecution(mimic != nil, trace.Size())
+		if f.stats.IsBuggy(iteration) {
+			last, _ := stateTrace.Get(stateTrace.Size() - 1)
+			f.stats.recordBug(BugRecord{
+				Iteration: i,
+				Elapsed:   time.Since(start),
+				Signature: bugSignature(f.violation, last),
+			})
+		}
+		numNewStates, _ := f.config.Guider.Check(trace, eventTrace, stateTrace)
+		if mimic != nil {
+			if origin, ok := f.mutationOrigins[mimic]; ok {
+				delete(f.mutationOrigins, mimic)
+				mimic = origin
+			}
+			mutatorFeedback(f.config.Mutator, mimic, numNewStates)
+		}
+		if numNewStates > 0 {
+			f.stats.recordInteresting()
 			numMutations := numNewStates * f.config.MutPerTrace
+			parentIssues := f.validator.Validate(trace)
 			for j := 0; j < numMutations; j++ {
 				new, ok := f.config.Mutator.Mutate(trace, eventTrace)
+				name := mutatorName(f.config.Mutator)
+				f.stats.recordMutation(name, ok)
 				if ok {
-					f.mutatedTracesQueue.Push(copyTrace(new, defaultCopyFilter()))
+					f.pushMutation(name, new, parentIssues)
 				}
 			}
 		}
 		coverages = append(coverages, f.config.Guider.Coverage())
+		f.elapsed = append(f.elapsed, time.Since(start))
+		if f.config.Observer != nil {
+			f.config.Observer.Observe(FuzzerProgress{
+				Iteration:  i + 1,
+				Iterations: f.config.Iterations,
+				Elapsed:    f.elapsed[i],
+				Coverage:   coverages[i],
+				QueueSize:  f.mutatedTracesQueue.Size(),
+				Stats:      f.stats,
+			})
+		}
+	}
+	if invalid := formatInvalidTraceStats(f.stats.Snapshot().InvalidTraces); invalid != "" {
+		fmt.Printf("\nRepaired mutated traces:\n%s", invalid)
+	}
+	if reporter, ok := f.config.Mutator.(MutatorReporter); ok {
+		stats := reporter.MutatorStats()
+		fmt.Printf("\nLearned mutator distribution:\n%s", formatMutatorStats(stats))
+		f.stats.setMutators(stats)
 	}
 	return coverages
 }
 
-func (f *Fuzzer) RunIteration(iteration string, mimic *List[*SchedulingChoice]) (*List[*SchedulingChoice], *List[*Event]) {
+// pushMutation repairs the mutated trace and queues it. The mutator is blamed
+// for the issues that the parent trace did not have already, recorded traces
+// contain ineffective crashes for instance.
+func (f *Fuzzer) pushMutation(name string, trace *List[*SchedulingChoice], parentIssues []TraceIssue) {
+	repaired, issues := f.validator.Normalize(trace)
+	f.stats.recordRepair(name, newIssues(issues, parentIssues))
+	f.mutationOrigins[repaired] = trace
+	f.mutatedTracesQueue.Push(repaired)
+}
+
+func (f *Fuzzer) RunIteration(iteration string, mimic *List[*SchedulingChoice]) (*List[*SchedulingChoice], *List[*Event], *List[*EnvState]) {
+	beginEpisode(f.config.Guider)
 	// Setup the context for the iterations
 	tCtx := &traceCtx{
 		trace:          NewList[*SchedulingChoice](),
 		eventTrace:     NewList[*Event](),
+		stateTrace:     NewList[*EnvState](),
 		nodeChoices:    NewQueue[*SchedulingChoice](),
 		booleanChoices: NewQueue[bool](),
 		integerChoices: NewQueue[int](),
 		crashPoints:    make(map[int]uint64),
 		startPoints:    make(map[int]uint64),
 		clientRequests: make(map[int]int),
-		rand:           f.rand,
+		duplicates:     make(map[int][2]uint64),
+		strategy:       f.config.Strategy,
 		fuzzer:         f,
 	}
+	f.config.Strategy.Reset(f.config.Steps)
 	if mimic != nil {
 		tCtx.mimicTrace = mimic
 		for i := 0; i < mimic.Size(); i++ {
@@ -325,43 +403,14 @@ func (f *Fuzzer) RunIteration(iteration string, mimic *List[*SchedulingChoice])
 				tCtx.crashPoints[ch.Step] = ch.Node
 			case ClientRequest:
 				tCtx.clientRequests[ch.Step] = ch.Request
+			case DuplicateMessage:
+				tCtx.duplicates[ch.Step] = [2]uint64{ch.From, ch.To}
 			}
 		}
 	} else {
-		for i := 0; i < f.config.Steps; i++ {
-			var fromIdx int = 0
-			for fromIdx == 0 {
-				fromIdx = f.rand.Intn(len(f.nodes))
-			}
-			var toIdx int = 0
-			for toIdx == 0 {
-				toIdx = f.rand.Intn(len(f.nodes))
-			}
-			tCtx.nodeChoices.Push(&SchedulingChoice{
-				Type:        Node,
-				From:        f.nodes[fromIdx],
-				To:          f.nodes[toIdx],
-				MaxMessages: f.rand.Intn(f.config.MaxMessages),
-			})
-		}
-		choices := make([]int, f.config.Steps)
-		for i := 0; i < f.config.Steps; i++ {
-			choices[i] = i
-		}
-		for _, c := range sample(choices, f.config.CrashQuota, f.rand) {
-			var idx int = 0
-			for idx == 0 {
-				idx = f.rand.Intn(len(f.nodes))
-			}
-			tCtx.crashPoints[c] = uint64(idx)
-			s := sample(intRange(c, f.config.Steps), 1, f.rand)[0]
-			tCtx.startPoints[s] = uint64(idx)
-		}
-		i := 1
-		for _, req := range sample(choices, f.config.NumberRequests, f.rand) {
-			tCtx.clientRequests[req] = i
-			i++
-		}
+		replicas := f.nodes[1:]
+		tCtx.crashPoints, tCtx.startPoints = f.config.Strategy.GetCrashPoints(f.config.Steps, f.config.CrashQuota, replicas)
+		tCtx.clientRequests = f.config.Strategy.GetClientRequests(f.config.Steps, f.config.NumberRequests)
 	}
 
 	// Reset the queues and environment
@@ -369,11 +418,15 @@ func (f *Fuzzer) RunIteration(iteration string, mimic *List[*SchedulingChoice])
 		q.Reset()
 	}
 	f.raftEnvironment.Reset(&FuzzContext{traceCtx: tCtx})
+	tCtx.stateTrace.Append(f.snapshotState(0))
 
 	crashed := make(map[uint64]bool)
+	// The last append delivered on every channel, to be duplicated
+	appends := make(map[string]pb.Message)
 	fCtx := &FuzzContext{traceCtx: tCtx}
 EpisodeLoop:
 	for j := 0; j < f.config.Steps; j++ {
+		tCtx.step = j
 		if toCrash, ok := tCtx.CanCrash(j); ok {
 			f.raftEnvironment.Stop(fCtx, toCrash)
 			if tCtx.IsError() {
@@ -395,7 +448,21 @@ EpisodeLoop:
 		if _, ok := crashed[to]; !ok {
 			messages := f.Schedule(from, to, maxMessages)
 			for _, m := range messages {
-				recordReceive(m, tCtx.eventTrace)
+				if m.Type == pb.MsgApp {
+					appends[fmt.Sprintf("%d_%d", from, to)] = m
+				}
+				recordReceive(m, tCtx)
+				f.raftEnvironment.Step(fCtx, m)
+				if tCtx.IsError() {
+					break EpisodeLoop
+				}
+			}
+		}
+
+		if from, to, ok := tCtx.IsDuplicate(j); ok {
+			m, ok := appends[fmt.Sprintf("%d_%d", from, to)]
+			if _, isCrashed := crashed[to]; ok && !isCrashed {
+				recordReceive(m, tCtx)
 				f.raftEnvironment.Step(fCtx, m)
 				if tCtx.IsError() {
 					break EpisodeLoop
@@ -418,39 +485,76 @@ EpisodeLoop:
 		}
 
 		for _, n := range f.raftEnvironment.Tick(fCtx) {
-			recordSend(n, tCtx.eventTrace)
+			recordSend(n, tCtx)
 			key := fmt.Sprintf("%d_%d", n.From, n.To)
 			f.messageQueues[key].Push(n)
 		}
+		tCtx.stateTrace.Append(f.snapshotState(j + 1))
 	}
 	if tCtx.IsError() {
-		errS := tCtx.GetError().Error()
-		f.stats["execution_errors"].(map[string]bool)[errS] = true
-		if _, ok := f.stats["error_executions"].(map[string][]string)[errS]; !ok {
-			f.stats["error_executions"].(map[string][]string)[errS] = make([]string, 0)
-		}
-		f.stats["error_executions"].(map[string][]string)[errS] = append(f.stats["error_executions"].(map[string][]string)[errS], iteration)
+		f.stats.recordError(iteration, tCtx.GetError().Error())
 	}
 
-	if f.config.Checker != nil && !f.config.Checker(f.raftEnvironment) {
-		buggyExecutions := f.stats["buggy_executions"].(map[string]bool)
-		buggyExecutions[iteration] = true
-		f.stats["buggy_executions"] = buggyExecutions
+	f.violation = ""
+	if f.config.Checker != nil {
+		f.violation = f.config.Checker(f.raftEnvironment)
+	}
+	if f.violation != "" {
+		f.stats.recordBuggy(iteration, f.checkerName())
 	}
 
-	return tCtx.trace, tCtx.eventTrace
+	return tCtx.trace, tCtx.eventTrace, tCtx.stateTrace
 }
 
 type Mutator interface {
 	Mutate(*List[*SchedulingChoice], *List[*Event]) (*List[*SchedulingChoice], bool)
 }
 
+// StatefulMutator is implemented by mutators that keep state across the
+// traces of a run, it is reset at the end of the run
+type StatefulMutator interface {
+	Mutator
+	Reset()
+}
+
+func resetMutator(m Mutator) {
+	if s, ok := m.(StatefulMutator); ok {
+		s.Reset()
+	}
+}
+
+// Seeded is implemented by the strategies and mutators that draw random
+// numbers, Seed restarts their random source so that a run can be repeated
+type Seeded interface {
+	Seed(seed int64)
+}
+
+// seedRandom seeds the strategy or mutator, a zero seed keeps the source
+// seeded with the time
+func seedRandom(x interface{}, seed int64) {
+	if s, ok := x.(Seeded); ok && seed != 0 {
+		s.Seed(seed)
+	}
+}
+
+func mutatorFeedback(m Mutator, trace *List[*SchedulingChoice], newStates int) {
+	if f, ok := m.(FeedbackMutator); ok {
+		f.Feedback(trace, newStates)
+	}
+}
+
+func discardFeedback(m Mutator) {
+	if f, ok := m.(FeedbackMutator); ok {
+		f.Discard()
+	}
+}
+
 type FuzzContext struct {
 	traceCtx *traceCtx
 }
 
 func (f *FuzzContext) AddEvent(e *Event) {
-	f.traceCtx.eventTrace.Append(e)
+	f.traceCtx.addEvent(e)
 }
 
 func (f *FuzzContext) RandomBooleanChoice() bool {
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// MutationStats counts the calls to a mutator and the traces it returned
+type MutationStats struct {
+	Attempted int
+	Accepted  int
+}
+
+// FuzzerStats are the counters of a fuzzer run. The fuzzer updates them as it
+// runs, readers take a Snapshot.
+type FuzzerStats struct {
+	RandomExecutions  int
+	MutatedExecutions int
+	// Corpus counts the traces that found new coverage
+	Corpus int
+	// Errors counts the executions that ended with an error, by error, and
+	// ErrorExecutions lists the iterations of every error
+	Errors          map[string]int
+	ErrorExecutions map[string][]string
+	// BuggyExecutions are the iterations in which the checker failed
+	BuggyExecutions map[string]bool
+	Bugs            []BugRecord
+	// BugsByChecker counts the failures of every checker
+	BugsByChecker map[string]int
+	// Mutations are the mutations of every mutator, named by the mutation for
+	// the mutators that choose between others
+	Mutations     map[string]*MutationStats
+	InvalidTraces map[string]*InvalidTraceStats
+	// Mutators is the distribution learned by the mutator, when it learns one
+	Mutators           []MutatorStats `json:",omitempty"`
+	TraceLength        int
+	AverageTraceLength float64
+
+	lock sync.Mutex
+}
+
+func NewFuzzerStats() *FuzzerStats {
+	return &FuzzerStats{
+		Errors:          make(map[string]int),
+		ErrorExecutions: make(map[string][]string),
+		BuggyExecutions: make(map[string]bool),
+		Bugs:            make([]BugRecord, 0),
+		BugsByChecker:   make(map[string]int),
+		Mutations:       make(map[string]*MutationStats),
+		InvalidTraces:   make(map[string]*InvalidTraceStats),
+	}
+}
+
+// recordExecution counts an execution of Run, mutated when it mimicked a
+// mutated trace
+func (s *FuzzerStats) recordExecution(mutated bool, traceLength int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if mutated {
+		s.MutatedExecutions++
+	} else {
+		s.RandomExecutions++
+	}
+	s.TraceLength += traceLength
+	s.AverageTraceLength = float64(s.TraceLength) / float64(s.RandomExecutions+s.MutatedExecutions)
+}
+
+func (s *FuzzerStats) recordInteresting() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Corpus++
+}
+
+func (s *FuzzerStats) recordError(iteration string, err string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Errors[err]++
+	s.ErrorExecutions[err] = append(s.ErrorExecutions[err], iteration)
+}
+
+func (s *FuzzerStats) recordBuggy(iteration string, checker string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.BuggyExecutions[iteration] = true
+	s.BugsByChecker[checker]++
+}
+
+func (s *FuzzerStats) recordBug(bug BugRecord) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Bugs = append(s.Bugs, bug)
+}
+
+func (s *FuzzerStats) recordMutation(name string, accepted bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	m, ok := s.Mutations[name]
+	if !ok {
+		m = &MutationStats{}
+		s.Mutations[name] = m
+	}
+	m.Attempted++
+	if accepted {
+		m.Accepted++
+	}
+}
+
+// recordRepair counts a mutated trace of the mutator and the issues it
+// introduced
+func (s *FuzzerStats) recordRepair(name string, introduced []TraceIssue) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stats, ok := s.InvalidTraces[name]
+	if !ok {
+		stats = &InvalidTraceStats{Issues: make(map[TraceIssue]int)}
+		s.InvalidTraces[name] = stats
+	}
+	stats.Mutations++
+	if len(introduced) > 0 {
+		stats.Invalid++
+		for _, issue := range introduced {
+			stats.Issues[issue]++
+		}
+	}
+}
+
+func (s *FuzzerStats) setMutators(mutators []MutatorStats) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Mutators = mutators
+}
+
+// IsBuggy tells whether the checker failed in the iteration
+func (s *FuzzerStats) IsBuggy(iteration string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.BuggyExecutions[iteration]
+}
+
+// Snapshot returns a copy of the counters that is not updated further
+func (s *FuzzerStats) Snapshot() *FuzzerStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c := NewFuzzerStats()
+	c.RandomExecutions = s.RandomExecutions
+	c.MutatedExecutions = s.MutatedExecutions
+	c.Corpus = s.Corpus
+	for err, count := range s.Errors {
+		c.Errors[err] = count
+	}
+	for err, iterations := range s.ErrorExecutions {
+		c.ErrorExecutions[err] = append([]string{}, iterations...)
+	}
+	for iteration := range s.BuggyExecutions {
+		c.BuggyExecutions[iteration] = true
+	}
+	c.Bugs = append(c.Bugs, s.Bugs...)
+	for checker, count := range s.BugsByChecker {
+		c.BugsByChecker[checker] = count
+	}
+	for name, m := range s.Mutations {
+		copied := *m
+		c.Mutations[name] = &copied
+	}
+	for name, stats := range s.InvalidTraces {
+		copied := &InvalidTraceStats{Mutations: stats.Mutations, Invalid: stats.Invalid, Issues: make(map[TraceIssue]int)}
+		for issue, count := range stats.Issues {
+			copied.Issues[issue] = count
+		}
+		c.InvalidTraces[name] = copied
+	}
+	c.Mutators = append(c.Mutators, s.Mutators...)
+	c.TraceLength = s.TraceLength
+	c.AverageTraceLength = s.AverageTraceLength
+	return c
+}
+
+func (s *FuzzerStats) MarshalJSON() ([]byte, error) {
+	// The alias drops the method, marshalling the snapshot does not recurse
+	type stats FuzzerStats
+	return json.Marshal((*stats)(s.Snapshot()))
+}
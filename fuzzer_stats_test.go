@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestFuzzerStatsConcurrentReads(t *testing.T) {
+	stats := NewFuzzerStats()
+	wg := new(sync.WaitGroup)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			stats.recordExecution(i%2 == 0, 10)
+			stats.recordMutation("swapNode", i%3 != 0)
+			stats.recordRepair("swapNode", []TraceIssue{DanglingCrash})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if _, err := json.Marshal(stats); err != nil {
+				t.Error(err)
+				return
+			}
+			stats.Snapshot()
+		}
+	}()
+	wg.Wait()
+
+	s := stats.Snapshot()
+	if s.RandomExecutions != 50 || s.MutatedExecutions != 50 || s.AverageTraceLength != 10 {
+		t.Errorf("unexpected executions: %+v", s)
+	}
+	if m := s.Mutations["swapNode"]; m.Attempted != 100 || m.Accepted != 66 {
+		t.Errorf("unexpected mutations: %+v", m)
+	}
+	stats.recordMutation("swapNode", true)
+	if s.Mutations["swapNode"].Attempted != 100 {
+		t.Error("expected the snapshot to not change")
+	}
+}
+
+func TestFuzzerStatsJSON(t *testing.T) {
+	config := testFuzzerConfig(NewNativeStateGuider(DefaultAbstraction(), "", false), CombineMutators(NewSwapNodeMutator(5)))
+	config.Iterations = 10
+	config.Checker = func(*RaftEnvironment) string { return "always" }
+	config.CheckerName = "always"
+	f := NewFuzzer(config)
+	f.Run()
+
+	data, err := json.Marshal(f.stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &FuzzerStats{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.RandomExecutions+decoded.MutatedExecutions != config.Iterations || len(decoded.Bugs) != config.Iterations {
+		t.Errorf("unexpected executions in %s", data)
+	}
+	// The executions of the seed population are checked as well
+	if decoded.BugsByChecker["always"] != config.Iterations+config.SeedPopulationSize {
+		t.Errorf("expected the bugs of the checker in %s", data)
+	}
+	if decoded.AverageTraceLength == 0 || len(decoded.Mutations) == 0 {
+		t.Errorf("expected the trace length and mutations in %s", data)
+	}
+	// Decoded and zero stats have a usable lock
+	if _, err := json.Marshal(decoded); err != nil {
+		t.Error(err)
+	}
+	if _, err := json.Marshal(&FuzzerStats{}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFuzzerStatsErrors(t *testing.T) {
+	stats := NewFuzzerStats()
+	stats.recordError("fuzz_1", "timeout")
+	stats.recordError("fuzz_4", "timeout")
+	s := stats.Snapshot()
+	if s.Errors["timeout"] != 2 || !reflect.DeepEqual(s.ErrorExecutions["timeout"], []string{"fuzz_1", "fuzz_4"}) {
+		t.Errorf("unexpected errors %v %v", s.Errors, s.ErrorExecutions)
+	}
+}
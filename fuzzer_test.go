@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func testFuzzerConfig(guider Guider, mutator Mutator) *FuzzerConfig {
+	return &FuzzerConfig{
+		Iterations: 30,
+		Steps:      50,
+		Strategy:   NewRandomStrategy(),
+		Checker:    SerializabilityChecker(),
+		Guider:     guider,
+		Mutator:    mutator,
+		RaftEnvironmentConfig: RaftEnvironmentConfig{
+			Replicas:      3,
+			ElectionTick:  10,
+			HeartbeatTick: 2,
+			TicksPerStep:  2,
+		},
+		MutPerTrace:        2,
+		NumberRequests:     2,
+		CrashQuota:         2,
+		MaxMessages:        5,
+		SeedPopulationSize: 5,
+		ReseedFrequency:    10,
+	}
+}
+
+func countChoices(trace *List[*SchedulingChoice], t SchedulingChoiceType) int {
+	count := 0
+	for _, ch := range trace.Iter() {
+		if ch.Type == t {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRunIterationStrategies(t *testing.T) {
+	for _, name := range []string{"random", "roundrobin", "delay", "pos", "pct"} {
+		t.Run(name, func(t *testing.T) {
+			s, err := GetStrategy(name, 3, 2, 3, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := testFuzzerConfig(NewNativeStateGuider(DefaultAbstraction(), "", false), &EmptyMutator{})
+			config.Strategy = s
+			f := NewFuzzer(config)
+			trace, events, states := f.RunIteration("test", nil)
+			if n := countChoices(trace, Node); n != config.Steps {
+				t.Errorf("expected %d node choices, got %d", config.Steps, n)
+			}
+			if states.Size() != config.Steps+1 {
+				t.Errorf("expected %d states, got %d", config.Steps+1, states.Size())
+			}
+			if events.Size() == 0 {
+				t.Error("expected events to be recorded")
+			}
+		})
+	}
+}
+
+func TestRunIterationMimic(t *testing.T) {
+	f := NewFuzzer(testFuzzerConfig(NewNativeStateGuider(DefaultAbstraction(), "", false), &EmptyMutator{}))
+	trace, _, _ := f.RunIteration("original", nil)
+	replayed, _, _ := f.RunIteration("replayed", trace)
+
+	original := copyTrace(trace, func(sc *SchedulingChoice) bool { return sc.Type == Node })
+	mimicked := copyTrace(replayed, func(sc *SchedulingChoice) bool { return sc.Type == Node })
+	if original.Size() != mimicked.Size() {
+		t.Fatalf("expected %d node choices, got %d", original.Size(), mimicked.Size())
+	}
+	for i, ch := range original.Iter() {
+		other, _ := mimicked.Get(i)
+		if ch.From != other.From || ch.To != other.To || ch.MaxMessages != other.MaxMessages {
+			t.Fatalf("choice %d differs: %v != %v", i, ch, other)
+		}
+	}
+	if countChoices(trace, StopNode) != countChoices(replayed, StopNode) {
+		t.Errorf("expected the crashes to be replayed")
+	}
+}
+
+func TestReplayRecordedTrace(t *testing.T) {
+	recordPath := path.Join(t.TempDir(), "traces")
+	config := testFuzzerConfig(NewNativeStateGuider(DefaultAbstraction(), recordPath, true), &EmptyMutator{})
+	config.Iterations = 1
+	config.RaftEnvironmentConfig.Seed = 7
+	NewFuzzer(config).Run()
+
+	trace, env, err := readRecordedTrace(path.Join(recordPath, "0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env == nil || env.Raft != config.RaftEnvironmentConfig || env.Steps != config.Steps || env.MaxMessages != config.MaxMessages {
+		t.Fatalf("unexpected environment: %+v", env)
+	}
+	if countChoices(trace, ClientRequest) == 0 {
+		t.Fatal("expected client requests in the trace")
+	}
+
+	// Replaying in the recorded environment runs the same execution
+	data, _ := os.ReadFile(path.Join(recordPath, "0.json"))
+	recorded := struct {
+		EventTrace *List[*Event] `json:"event_trace"`
+	}{}
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		t.Fatal(err)
+	}
+	replay := NewFuzzer(&FuzzerConfig{
+		Iterations:            1,
+		Steps:                 env.Steps,
+		Strategy:              NewRandomStrategy(),
+		RaftEnvironmentConfig: env.Raft,
+		MaxMessages:           env.MaxMessages,
+	})
+	_, eventTrace, _ := replay.RunIteration("replay", trace)
+	// The environment records the events of the nodes of a step in map order
+	events := func(trace *List[*Event]) []string {
+		encoded := make([]string, 0, trace.Size())
+		for _, e := range trace.Iter() {
+			// Decoded entries are maps, encode both sides from maps
+			var decoded interface{}
+			data, _ := json.Marshal(e)
+			json.Unmarshal(data, &decoded)
+			data, _ = json.Marshal(decoded)
+			encoded = append(encoded, string(data))
+		}
+		sort.Strings(encoded)
+		return encoded
+	}
+	replayed, original := events(eventTrace), events(recorded.EventTrace)
+	if strings.Join(replayed, "\n") != strings.Join(original, "\n") {
+		t.Errorf("expected the replay to produce the %d recorded events, got %d", len(original), len(replayed))
+	}
+}
+
+func TestFuzzerRun(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	guider := NewTLCStateGuider(tlc.Client(), "", false)
+	mutator := CombineMutators(NewSwapCrashNodeMutator(1), NewSwapNodeMutator(5), NewSwapMaxMessagesMutator(5))
+	f := NewFuzzer(testFuzzerConfig(guider, mutator))
+
+	coverages := f.Run()
+	if len(coverages) != 30 {
+		t.Fatalf("expected 30 coverage points, got %d", len(coverages))
+	}
+	for i := 1; i < len(coverages); i++ {
+		if coverages[i].UniqueStates < coverages[i-1].UniqueStates {
+			t.Fatalf("coverage decreased at iteration %d", i)
+		}
+	}
+	if coverages[len(coverages)-1].UniqueStates == 0 {
+		t.Error("expected states to be covered")
+	}
+	if f.stats.Snapshot().MutatedExecutions == 0 {
+		t.Error("expected mutated traces to be executed")
+	}
+	if tlc.Requests() == 0 {
+		t.Error("expected traces to be sent to tlc")
+	}
+}
+
+func TestFuzzerRecordsBugs(t *testing.T) {
+	config := testFuzzerConfig(NewNativeStateGuider(DefaultAbstraction(), "", false), &EmptyMutator{})
+	config.Iterations = 4
+	config.Checker = func(*RaftEnvironment) string { return "always" }
+	f := NewFuzzer(config)
+	f.Run()
+	records := f.stats.Snapshot().Bugs
+	if len(records) != config.Iterations {
+		t.Fatalf("expected a bug in every iteration, got %d", len(records))
+	}
+	for i, r := range records {
+		if r.Iteration != i || !strings.HasPrefix(r.Signature, "always;") {
+			t.Errorf("unexpected bug record %d: %+v", i, r)
+		}
+		if i > 0 && r.Elapsed < records[i-1].Elapsed {
+			t.Errorf("expected the bugs in the order they were found")
+		}
+	}
+}
@@ -6,29 +6,46 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path"
 	"strconv"
 	"strings"
 	"sync"
-
-	"github.com/zeu5/gocov"
 )
 
 type CoverageStats struct {
 	UniqueStates      int
 	UniqueTraces      int
 	UniqueStateTraces int
+	// Divergences counts the traces the TLA+ model could not follow
+	Divergences int
+	// UniqueEventTraces counts the distinct happens-before graphs of events
+	UniqueEventTraces int
+	// CoveredLines is the number of lines of the raft package covered
+	CoveredLines int
+	// CoveredEdges is the number of blocks of the raft package hit and
+	// EdgeFeatures the number of distinct (block, hit count bucket) pairs
+	CoveredEdges int
+	EdgeFeatures int
+	// FallbackEpisodes counts the episodes checked with the abstract states of
+	// the environment while TLC was unreachable, FallbackStates the abstract
+	// states they covered. They are not TLC states and are kept out of
+	// UniqueStates.
+	FallbackEpisodes int
+	FallbackStates   int
+	// FailedEpisodes counts the episodes that could not be checked because TLC
+	// was unreachable and there was no fallback
+	FailedEpisodes int
 }
 
 type Guider interface {
-	Check(*List[*SchedulingChoice], *List[*Event]) (int, float64)
+	Check(*List[*SchedulingChoice], *List[*Event], *List[*EnvState]) (int, float64)
 	Coverage() CoverageStats
 	Reset(string)
 }
 
 type TLCStateGuider struct {
-	TLCAddr        string
 	statesMap      map[int64]bool
 	tracesMap      map[string]bool
 	stateTracesMap map[string]bool
@@ -36,13 +53,71 @@ type TLCStateGuider struct {
 	recordPath     string
 	recordTraces   bool
 	count          int
+	divergences    int
+	fallback       StateAbstraction
+	// tlcDown is set while TLC is unreachable, the episodes then try TLC once
+	// without retrying
+	tlcDown        bool
+	failedEpisodes int
+	// fallbackStates are the abstract states of the episodes checked without
+	// TLC
+	fallbackStates   map[int64]bool
+	fallbackEpisodes int
+	// tracker attributes the lines of the raft package to the traces that
+	// covered them first, when coverage is tracked
+	tracker       *coverageTracker
+	contributions []Contribution
+	iteration     int
+	runs          int
+	// environment is recorded with the traces so that they can be replayed
+	environment *TraceEnvironment
+	// name keeps the files of the guider apart from the ones of other guiders
+	// recording to the same path
+	name           string
+	warnedMismatch bool
+	// shared are the TLC states of the episode fetched by a MultiGuider
+	shared *tlcResult
 
 	lock *sync.Mutex
 }
 
-var _ Guider = &TLCStateGuider{}
+var _ EpisodeGuider = &TLCStateGuider{}
+
+// TraceEnvironment is the configuration a trace was run with, replaying the
+// trace needs the same one
+type TraceEnvironment struct {
+	Raft        RaftEnvironmentConfig
+	Steps       int
+	MaxMessages int
+}
+
+// EnvironmentGuider is implemented by guiders that record the traces along
+// with the environment they were run in
+type EnvironmentGuider interface {
+	Guider
+	SetEnvironment(TraceEnvironment)
+}
+
+func setEnvironment(g Guider, env TraceEnvironment) {
+	if e, ok := g.(EnvironmentGuider); ok {
+		e.SetEnvironment(env)
+	}
+}
+
+// NamedGuider is implemented by guiders that write files of their own, the
+// benchmark or guider name keeps them apart
+type NamedGuider interface {
+	Guider
+	SetName(string)
+}
+
+func setGuiderName(g Guider, name string) {
+	if n, ok := g.(NamedGuider); ok {
+		n.SetName(name)
+	}
+}
 
-func NewTLCStateGuider(tlcAddr, recordPath string, recordTraces bool) *TLCStateGuider {
+func NewTLCStateGuider(tlcClient *TLCClient, recordPath string, recordTraces bool) *TLCStateGuider {
 	if recordPath != "" {
 		if _, err := os.Stat(recordPath); err == nil {
 			os.RemoveAll(recordPath)
@@ -50,11 +125,11 @@ func NewTLCStateGuider(tlcAddr, recordPath string, recordTraces bool) *TLCStateG
 		os.Mkdir(recordPath, 0777)
 	}
 	return &TLCStateGuider{
-		TLCAddr:        tlcAddr,
 		statesMap:      make(map[int64]bool),
 		tracesMap:      make(map[string]bool),
 		stateTracesMap: make(map[string]bool),
-		tlcClient:      NewTLCClient(tlcAddr),
+		fallbackStates: make(map[int64]bool),
+		tlcClient:      tlcClient,
 		recordPath:     recordPath,
 		recordTraces:   recordTraces,
 		count:          0,
@@ -64,23 +139,238 @@ func NewTLCStateGuider(tlcAddr, recordPath string, recordTraces bool) *TLCStateG
 
 func (t *TLCStateGuider) Reset(key string) {
 	t.lock.Lock()
+	t.writeCoverageRecord(key)
+	t.runs += 1
 	t.statesMap = make(map[int64]bool)
 	t.tracesMap = make(map[string]bool)
 	t.stateTracesMap = make(map[string]bool)
+	t.fallbackStates = make(map[int64]bool)
+	t.fallbackEpisodes = 0
+	t.failedEpisodes = 0
+	t.divergences = 0
+	t.contributions = nil
+	t.iteration = 0
+	if t.tracker != nil {
+		t.tracker.reset()
+	}
 	t.lock.Unlock()
 }
 
+// writeCoverageRecord writes the coverage of the run along with the traces
+// that contributed to it to the contributions directory
+func (t *TLCStateGuider) writeCoverageRecord(key string) {
+	if t.recordPath == "" || !t.recordTraces {
+		return
+	}
+	record := &CoverageRecord{
+		Guider:           key,
+		Run:              t.runs,
+		States:           len(t.statesMap),
+		FallbackStates:   len(t.fallbackStates),
+		FallbackEpisodes: t.fallbackEpisodes,
+		Contributions:    t.contributions,
+	}
+	if t.tracker != nil {
+		record.Functions = t.tracker.functionCoverage()
+		record.LineHits = t.tracker.lineHits()
+	}
+	if err := writeCoverageRecord(t.recordPath, record); err != nil {
+		fmt.Printf("Error writing coverage record: %s\n", err)
+	}
+}
+
 func (t *TLCStateGuider) Coverage() CoverageStats {
 	t.lock.Lock()
 	defer t.lock.Unlock()
-	return CoverageStats{
+	c := CoverageStats{
 		UniqueStates:      len(t.statesMap),
 		UniqueTraces:      len(t.tracesMap),
 		UniqueStateTraces: len(t.stateTracesMap),
+		Divergences:       t.divergences,
+		FallbackEpisodes:  t.fallbackEpisodes,
+		FallbackStates:    len(t.fallbackStates),
+		FailedEpisodes:    t.failedEpisodes,
+	}
+	if t.tracker != nil {
+		c.CoveredLines = t.tracker.coveredLines()
+	}
+	return c
+}
+
+// TrackCoverage makes the guider attribute the lines of the raft package it
+// covers to the traces that reached them first. The binary has to be built
+// with -cover.
+func (t *TLCStateGuider) TrackCoverage() {
+	t.lock.Lock()
+	if t.tracker == nil {
+		t.tracker = newCoverageTracker()
+	}
+	t.lock.Unlock()
+}
+
+func (t *TLCStateGuider) BeginEpisode() {
+	t.lock.Lock()
+	if t.tracker != nil {
+		t.tracker.begin()
+	}
+	t.lock.Unlock()
+}
+
+func (t *TLCStateGuider) SetEnvironment(env TraceEnvironment) {
+	t.lock.Lock()
+	t.environment = &env
+	t.lock.Unlock()
+}
+
+func (t *TLCStateGuider) SetName(name string) {
+	t.lock.Lock()
+	t.name = name
+	t.lock.Unlock()
+}
+
+// SetFallback makes the guider use the abstract states of the environment
+// instead of failing the episode when the TLC server cannot be reached
+func (t *TLCStateGuider) SetFallback(abstraction StateAbstraction) {
+	t.fallback = abstraction
+}
+
+// tlcResult is the answer of TLC to the event trace of an episode
+type tlcResult struct {
+	states []State
+	err    error
+	// checked is set once a guider looked for a divergence in the states
+	checked bool
+}
+
+// fetchTLCStates sends the event trace to TLC
+func (t *TLCStateGuider) fetchTLCStates(eventTrace *List[*Event]) *tlcResult {
+	t.lock.Lock()
+	down := t.tlcDown
+	t.lock.Unlock()
+	result := &tlcResult{}
+	if down {
+		// Avoid waiting on the retries of an unreachable server
+		result.states, result.err = t.tlcClient.TrySendTrace(eventTrace)
+	} else {
+		result.states, result.err = t.tlcClient.SendTrace(eventTrace)
+	}
+	return result
+}
+
+// shareTLCStates makes the next Check use the states instead of sending the
+// trace to TLC again
+func (t *TLCStateGuider) shareTLCStates(result *tlcResult) {
+	t.lock.Lock()
+	t.shared = result
+	t.lock.Unlock()
+}
+
+func (t *TLCStateGuider) usesTLC() bool {
+	return t.tlcClient != nil
+}
+
+func (t *TLCStateGuider) Check(trace *List[*SchedulingChoice], eventTrace *List[*Event], stateTrace *List[*EnvState]) (int, float64) {
+	t.lock.Lock()
+	down := t.tlcDown
+	result := t.shared
+	t.shared = nil
+	t.lock.Unlock()
+	if result == nil {
+		result = t.fetchTLCStates(eventTrace)
+	}
+	tlcStates, err := result.states, result.err
+	if err != nil {
+		t.lock.Lock()
+		if !t.tlcDown {
+			fmt.Printf("\nError connecting to tlc: %s\n", err)
+		}
+		t.tlcDown = true
+		if t.fallback == nil {
+			t.failedEpisodes += 1
+			t.lock.Unlock()
+			return 0, 0
+		}
+		t.lock.Unlock()
+		return t.checkStates(trace, eventTrace, abstractStates(stateTrace, t.fallback), true)
+	}
+	if down {
+		t.lock.Lock()
+		t.tlcDown = false
+		t.lock.Unlock()
+		fmt.Println("\nTLC is reachable again")
+	}
+	if !result.checked {
+		result.checked = true
+		t.checkDivergence(trace, eventTrace, tlcStates)
+	}
+	return t.checkStates(trace, eventTrace, tlcStates, false)
+}
+
+// checkDivergence records the trace when the model stopped before the end of
+// it. The model returns the initial state and one state for every event it
+// could take, the first event without a state is the one it could not follow.
+func (t *TLCStateGuider) checkDivergence(trace *List[*SchedulingChoice], eventTrace *List[*Event], states []State) {
+	events := make([]*Event, 0, eventTrace.Size())
+	for _, e := range eventTrace.Iter() {
+		if !e.Reset {
+			events = append(events, e)
+		}
+	}
+	if len(states) == len(events)+1 {
+		return
+	}
+	t.lock.Lock()
+	if len(states) == 0 || len(states) > len(events)+1 {
+		// The response does not follow the events, it says nothing about
+		// where the model stopped
+		if !t.warnedMismatch {
+			fmt.Printf("\nExpected at most %d states from tlc, got %d, not checking for divergences\n", len(events)+1, len(states))
+			t.warnedMismatch = true
+		}
+		t.lock.Unlock()
+		return
+	}
+	t.divergences += 1
+	count := t.divergences
+	name := t.name
+	run := t.runs
+	t.lock.Unlock()
+
+	index := len(states) - 1
+	fmt.Printf("\n%sModel/implementation divergence at event %d (%s) of %d\n", namePrefix(name), index, events[index].Name, len(events))
+	// Divergences are conformance failures, they are saved even when the
+	// traces are not recorded
+	if t.recordPath == "" {
+		return
+	}
+	divergencePath := path.Join(t.recordPath, "divergences", name)
+	if err := os.MkdirAll(divergencePath, 0777); err != nil {
+		return
+	}
+	data := map[string]interface{}{
+		"trace":           trace,
+		"event_trace":     eventTrace,
+		"state_trace":     parseTLCStateTrace(states),
+		"unmatched_index": index,
+		"unmatched_event": events[index],
+	}
+	dataB, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return
+	}
+	os.WriteFile(path.Join(divergencePath, fmt.Sprintf("%d_%d.json", run, count)), dataB, 0644)
+}
+
+func namePrefix(name string) string {
+	if name == "" {
+		return ""
 	}
+	return name + ": "
 }
 
-func (t *TLCStateGuider) Check(trace *List[*SchedulingChoice], eventTrace *List[*Event]) (int, float64) {
+// checkStates counts the new states of the trace, the abstract states of a
+// fallback episode are counted apart from the TLC states
+func (t *TLCStateGuider) checkStates(trace *List[*SchedulingChoice], eventTrace *List[*Event], states []State, fallback bool) (int, float64) {
 	bs, _ := json.Marshal(trace)
 	sum := sha256.Sum256(bs)
 	hash := hex.EncodeToString(sum[:])
@@ -92,46 +382,87 @@ func (t *TLCStateGuider) Check(trace *List[*SchedulingChoice], eventTrace *List[
 	t.lock.Unlock()
 
 	t.lock.Lock()
-	curStates := len(t.statesMap)
+	statesMap := t.statesMap
+	if fallback {
+		statesMap = t.fallbackStates
+		t.fallbackEpisodes += 1
+	}
+	curStates := len(statesMap)
 	t.lock.Unlock()
 	numNewStates := 0
-	if tlcStates, err := t.tlcClient.SendTrace(eventTrace); err == nil {
-		t.recordTrace(trace, eventTrace, tlcStates)
-		for _, s := range tlcStates {
-			t.lock.Lock()
-			_, ok := t.statesMap[s.Key]
-			if !ok {
-				numNewStates += 1
-				t.statesMap[s.Key] = true
-			}
-			t.lock.Unlock()
-		}
-		bs, _ := json.Marshal(tlcStates)
-		sum := sha256.Sum256(bs)
-		stateTraceHash := hex.EncodeToString(sum[:])
+	newStates := make([]int64, 0)
+	t.recordTrace(hash, trace, eventTrace, states)
+	for _, s := range states {
 		t.lock.Lock()
-		if _, ok := t.stateTracesMap[stateTraceHash]; !ok {
-			// fmt.Printf("New state trace: %s\n", stateTraceHash)
-			t.stateTracesMap[stateTraceHash] = true
+		_, ok := statesMap[s.Key]
+		if !ok {
+			numNewStates += 1
+			newStates = append(newStates, s.Key)
+			statesMap[s.Key] = true
 		}
 		t.lock.Unlock()
-	} else {
-		panic(fmt.Sprintf("error connecting to tlc: %s", err))
 	}
+	t.recordContribution(hash, newStates, fallback)
+	if fallback {
+		return numNewStates, float64(numNewStates) / float64(max(curStates, 1))
+	}
+	bs, _ = json.Marshal(states)
+	sum = sha256.Sum256(bs)
+	stateTraceHash := hex.EncodeToString(sum[:])
+	t.lock.Lock()
+	if _, ok := t.stateTracesMap[stateTraceHash]; !ok {
+		// fmt.Printf("New state trace: %s\n", stateTraceHash)
+		t.stateTracesMap[stateTraceHash] = true
+	}
+	t.lock.Unlock()
 	return numNewStates, float64(numNewStates) / float64(max(curStates, 1))
 }
 
-func (t *TLCStateGuider) recordTrace(trace *List[*SchedulingChoice], eventTrace *List[*Event], states []State) {
+// recordContribution remembers what the trace added to the coverage when it
+// added anything and the traces are recorded
+func (t *TLCStateGuider) recordContribution(traceHash string, newStates []int64, fallback bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.iteration += 1
+	c := Contribution{Iteration: t.iteration - 1}
+	if fallback {
+		c.NewFallbackStates = newStates
+	} else {
+		c.NewStates = newStates
+	}
+	if t.tracker != nil {
+		c.NewLines, c.NewFunctions = t.tracker.update()
+	}
+	if t.recordPath == "" || !t.recordTraces || (len(newStates) == 0 && len(c.NewLines) == 0) {
+		return
+	}
+	c.TraceHash = traceHash
+	t.contributions = append(t.contributions, c)
+}
+
+// recordTrace writes the trace to the directory of the guider under the
+// record path, named guiders sharing the record path keep their own traces
+func (t *TLCStateGuider) recordTrace(traceHash string, trace *List[*SchedulingChoice], eventTrace *List[*Event], states []State) {
 	if !t.recordTraces {
 		return
 	}
-	filePath := path.Join(t.recordPath, strconv.Itoa(t.count)+".json")
-	t.count += 1
 	data := map[string]interface{}{
 		"trace":       trace,
+		"trace_hash":  traceHash,
 		"event_trace": eventTrace,
 		"state_trace": parseTLCStateTrace(states),
 	}
+	t.lock.Lock()
+	if t.environment != nil {
+		data["environment"] = t.environment
+	}
+	tracePath := path.Join(t.recordPath, t.name)
+	t.lock.Unlock()
+	if err := os.MkdirAll(tracePath, 0777); err != nil {
+		return
+	}
+	filePath := path.Join(tracePath, strconv.Itoa(t.count)+".json")
+	t.count += 1
 	dataB, err := json.MarshalIndent(data, "", "\t")
 	if err != nil {
 		return
@@ -146,6 +477,27 @@ func (t *TLCStateGuider) recordTrace(trace *List[*SchedulingChoice], eventTrace
 	writer.Flush()
 }
 
+// readRecordedTrace reads back the scheduling choices of a trace written by
+// recordTrace so that it can be replayed, along with its environment when the
+// file records one
+func readRecordedTrace(filePath string) (*List[*SchedulingChoice], *TraceEnvironment, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading trace file: %s", err)
+	}
+	recorded := struct {
+		Trace       *List[*SchedulingChoice] `json:"trace"`
+		Environment *TraceEnvironment        `json:"environment"`
+	}{}
+	if err = json.Unmarshal(data, &recorded); err != nil {
+		return nil, nil, fmt.Errorf("error parsing trace file: %s", err)
+	}
+	if recorded.Trace == nil {
+		return nil, nil, fmt.Errorf("no trace in file: %s", filePath)
+	}
+	return recorded.Trace, recorded.Environment, nil
+}
+
 func parseTLCStateTrace(states []State) []State {
 	newStates := make([]State, len(states))
 	for i, s := range states {
@@ -169,15 +521,15 @@ type TraceCoverageGuider struct {
 
 var _ Guider = &TraceCoverageGuider{}
 
-func NewTraceCoverageGuider(tlcAddr, recordPath string, recordTraces bool) *TraceCoverageGuider {
+func NewTraceCoverageGuider(tlcClient *TLCClient, recordPath string, recordTraces bool) *TraceCoverageGuider {
 	return &TraceCoverageGuider{
 		traces:         make(map[string]bool),
-		TLCStateGuider: NewTLCStateGuider(tlcAddr, recordPath, recordTraces),
+		TLCStateGuider: NewTLCStateGuider(tlcClient, recordPath, recordTraces),
 	}
 }
 
-func (t *TraceCoverageGuider) Check(trace *List[*SchedulingChoice], events *List[*Event]) (int, float64) {
-	t.TLCStateGuider.Check(trace, events)
+func (t *TraceCoverageGuider) Check(trace *List[*SchedulingChoice], events *List[*Event], states *List[*EnvState]) (int, float64) {
+	t.TLCStateGuider.Check(trace, events, states)
 
 	eTrace := newEventTrace(events)
 	key := eTrace.Hash()
@@ -197,6 +549,7 @@ func (t *TraceCoverageGuider) Coverage() CoverageStats {
 	c := t.TLCStateGuider.Coverage()
 	t.lock.Lock()
 	c.UniqueTraces = len(t.traces)
+	c.UniqueEventTraces = len(t.traces)
 	t.lock.Unlock()
 	return c
 }
@@ -260,47 +613,190 @@ func newEventTrace(events *List[*Event]) *eventTrace {
 	return eTrace
 }
 
+// LineCoverageGuider is guided by the lines of the raft package covered, the
+// binary has to be built with -cover
 type LineCoverageGuider struct {
-	covData *gocov.Coverage
 	*TLCStateGuider
 }
 
-func NewLineCoverageGuider(tlcAddr, recordPath string, recordTraces bool) *LineCoverageGuider {
-	return &LineCoverageGuider{
-		covData:        nil,
-		TLCStateGuider: NewTLCStateGuider(tlcAddr, recordPath, recordTraces),
+func NewLineCoverageGuider(tlcClient *TLCClient, recordPath string, recordTraces bool) *LineCoverageGuider {
+	l := &LineCoverageGuider{
+		TLCStateGuider: NewTLCStateGuider(tlcClient, recordPath, recordTraces),
 	}
+	l.TrackCoverage()
+	return l
 }
 
 var _ Guider = &LineCoverageGuider{}
 
-func (l *LineCoverageGuider) Check(trace *List[*SchedulingChoice], events *List[*Event]) (int, float64) {
-	l.TLCStateGuider.Check(trace, events)
-	cov, err := gocov.GetCoverage(gocov.CoverageConfig{
-		MatchPkgs: []string{"github.com/zeu5/raft-fuzzing/raft"},
-	})
-	if err != nil {
-		fmt.Println("Error reading coverage data: " + err.Error())
-		return 0, 0
-	}
+func (l *LineCoverageGuider) Check(trace *List[*SchedulingChoice], events *List[*Event], states *List[*EnvState]) (int, float64) {
+	l.lock.Lock()
+	curLines := l.tracker.coveredLines()
+	l.lock.Unlock()
+	l.TLCStateGuider.Check(trace, events, states)
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	if l.covData == nil {
-		l.covData = cov
-		return cov.GetCoveredLines(), 1
-	}
-	curLines := l.covData.GetCoveredLines()
-	l.covData.Data.Merge(cov.Data)
-	updatedLines := l.covData.GetCoveredLines()
-	newLines := updatedLines - curLines
+	newLines := l.tracker.coveredLines() - curLines
 	return newLines, float64(newLines) / float64(max(curLines, 1))
 }
 
 func (l *LineCoverageGuider) Reset(key string) {
 	l.lock.Lock()
-	fmt.Printf("Percentage of lines covered: %f\n", l.covData.GetPercent())
-	l.covData.Reset()
-	l.covData = nil
+	lines, covered := 0, 0
+	for _, f := range l.tracker.functionCoverage() {
+		lines += f.Lines
+		covered += f.CoveredLines
+	}
+	if lines > 0 {
+		fmt.Printf("Percentage of lines covered: %f\n", 100*float64(covered)/float64(lines))
+	}
 	l.lock.Unlock()
 	l.TLCStateGuider.Reset(key)
 }
+
+// NativeStateGuider measures coverage over abstract states computed from the
+// environment itself and does not need a TLC server
+type NativeStateGuider struct {
+	Abstraction StateAbstraction
+	*TLCStateGuider
+}
+
+var _ Guider = &NativeStateGuider{}
+
+func NewNativeStateGuider(abstraction StateAbstraction, recordPath string, recordTraces bool) *NativeStateGuider {
+	return &NativeStateGuider{
+		Abstraction:    abstraction,
+		TLCStateGuider: NewTLCStateGuider(nil, recordPath, recordTraces),
+	}
+}
+
+func (n *NativeStateGuider) Check(trace *List[*SchedulingChoice], events *List[*Event], states *List[*EnvState]) (int, float64) {
+	return n.checkStates(trace, events, abstractStates(states, n.Abstraction), false)
+}
+
+// WeightedGuider is a guider of a MultiGuider along with the weight of its
+// signal
+type WeightedGuider struct {
+	Name   string
+	Guider Guider
+	Weight float64
+}
+
+// MultiGuider combines the feedback of several guiders. A trace is interesting
+// when any of them finds something new, the number of mutations follows the
+// weighted sum of their signals.
+type MultiGuider struct {
+	Guiders []WeightedGuider
+}
+
+var _ EpisodeGuider = &MultiGuider{}
+
+func NewMultiGuider(guiders ...WeightedGuider) *MultiGuider {
+	return &MultiGuider{
+		Guiders: guiders,
+	}
+}
+
+// tlcGuider is implemented by the guiders built on TLCStateGuider
+type tlcGuider interface {
+	usesTLC() bool
+	fetchTLCStates(*List[*Event]) *tlcResult
+	shareTLCStates(*tlcResult)
+}
+
+func (m *MultiGuider) Check(trace *List[*SchedulingChoice], events *List[*Event], states *List[*EnvState]) (int, float64) {
+	// The guiders that use TLC share one request, the first of them looks
+	// for divergences
+	var shared *tlcResult
+	for _, g := range m.Guiders {
+		if tg, ok := g.Guider.(tlcGuider); ok && tg.usesTLC() {
+			if shared == nil {
+				shared = tg.fetchTLCStates(events)
+			}
+			tg.shareTLCStates(shared)
+		}
+	}
+	weighted := 0.0
+	weightedRatio := 0.0
+	interesting := false
+	for _, g := range m.Guiders {
+		numNew, ratio := g.Guider.Check(trace, events, states)
+		if numNew > 0 {
+			interesting = true
+		}
+		weighted += g.Weight * float64(numNew)
+		weightedRatio += g.Weight * ratio
+	}
+	if !interesting {
+		return 0, 0
+	}
+	return max(int(math.Round(weighted)), 1), weightedRatio
+}
+
+// Coverage reports the largest value of every signal among the guiders, the
+// guiders that do not measure a signal leave it at zero
+func (m *MultiGuider) Coverage() CoverageStats {
+	c := CoverageStats{}
+	for _, g := range m.Guiders {
+		gc := g.Guider.Coverage()
+		c.UniqueStates = max(c.UniqueStates, gc.UniqueStates)
+		c.UniqueTraces = max(c.UniqueTraces, gc.UniqueTraces)
+		c.UniqueStateTraces = max(c.UniqueStateTraces, gc.UniqueStateTraces)
+		c.Divergences = max(c.Divergences, gc.Divergences)
+		c.UniqueEventTraces = max(c.UniqueEventTraces, gc.UniqueEventTraces)
+		c.CoveredLines = max(c.CoveredLines, gc.CoveredLines)
+		c.CoveredEdges = max(c.CoveredEdges, gc.CoveredEdges)
+		c.EdgeFeatures = max(c.EdgeFeatures, gc.EdgeFeatures)
+		c.FallbackEpisodes = max(c.FallbackEpisodes, gc.FallbackEpisodes)
+		c.FallbackStates = max(c.FallbackStates, gc.FallbackStates)
+		c.FailedEpisodes = max(c.FailedEpisodes, gc.FailedEpisodes)
+	}
+	return c
+}
+
+func (m *MultiGuider) BeginEpisode() {
+	for _, g := range m.Guiders {
+		beginEpisode(g.Guider)
+	}
+}
+
+func (m *MultiGuider) SetEnvironment(env TraceEnvironment) {
+	for _, g := range m.Guiders {
+		setEnvironment(g.Guider, env)
+	}
+}
+
+func (m *MultiGuider) SetName(name string) {
+	for _, g := range m.Guiders {
+		setGuiderName(g.Guider, name+"_"+g.Name)
+	}
+}
+
+// Reset resets every guider with the key suffixed by its name, so that the
+// records they write do not overwrite each other
+func (m *MultiGuider) Reset(key string) {
+	for _, g := range m.Guiders {
+		g.Guider.Reset(key + "_" + g.Name)
+	}
+}
+
+// parseGuiderWeights reads weights given as name=weight, the names are the
+// ones of the guider flag
+func parseGuiderWeights(weights []string) (map[string]float64, error) {
+	parsed := make(map[string]float64)
+	for _, w := range weights {
+		name, value, ok := strings.Cut(w, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid guider weight %q, expected name=weight", w)
+		}
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight of guider %s: %s", name, value)
+		}
+		if _, ok := parsed[name]; ok {
+			return nil, fmt.Errorf("guider %s is given more than once", name)
+		}
+		parsed[name] = weight
+	}
+	return parsed, nil
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+)
+
+func runEpisodes(t *testing.T, guider Guider, episodes int) []int {
+	t.Helper()
+	f := NewFuzzer(testFuzzerConfig(guider, &EmptyMutator{}))
+	newStates := make([]int, episodes)
+	for i := 0; i < episodes; i++ {
+		trace, events, states := f.RunIteration("test", nil)
+		newStates[i], _ = guider.Check(trace, events, states)
+	}
+	return newStates
+}
+
+func TestGuiders(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	guiders := map[string]func() Guider{
+		"tlc":    func() Guider { return NewTLCStateGuider(tlc.Client(), "", false) },
+		"trace":  func() Guider { return NewTraceCoverageGuider(tlc.Client(), "", false) },
+		"line":   func() Guider { return NewLineCoverageGuider(tlc.Client(), "", false) },
+		"native": func() Guider { return NewNativeStateGuider(DefaultAbstraction(), "", false) },
+		"multi": func() Guider {
+			client := tlc.Client()
+			return NewMultiGuider(
+				WeightedGuider{Name: "tlc", Guider: NewTLCStateGuider(client, "", false), Weight: 1},
+				WeightedGuider{Name: "trace", Guider: NewTraceCoverageGuider(client, "", false), Weight: 1},
+			)
+		},
+	}
+	for name, newGuider := range guiders {
+		t.Run(name, func(t *testing.T) {
+			guider := newGuider()
+			runEpisodes(t, guider, 5)
+			cov := guider.Coverage()
+			if cov.UniqueTraces == 0 {
+				t.Error("expected traces to be covered")
+			}
+			if name != "line" && cov.UniqueStates == 0 {
+				t.Error("expected states to be covered")
+			}
+			guider.Reset(name)
+			if cov := guider.Coverage(); cov.UniqueStates != 0 || cov.UniqueTraces != 0 {
+				t.Errorf("expected empty coverage after reset, got %v", cov)
+			}
+		})
+	}
+}
+
+func TestTLCStateGuiderNewStates(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	guider := NewTLCStateGuider(tlc.Client(), "", false)
+	newStates := runEpisodes(t, guider, 10)
+	total := 0
+	for _, n := range newStates {
+		total += n
+	}
+	if total != guider.Coverage().UniqueStates {
+		t.Errorf("expected new states to add up to %d, got %d", guider.Coverage().UniqueStates, total)
+	}
+}
+
+func TestMultiGuider(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	client := tlc.Client()
+	native := NewNativeStateGuider(DefaultAbstraction(), "", false)
+	trace := NewTraceCoverageGuider(client, "", false)
+	line := NewLineCoverageGuider(client, "", false)
+	guider := NewMultiGuider(
+		WeightedGuider{Name: "native", Guider: native, Weight: 0},
+		WeightedGuider{Name: "trace", Guider: trace, Weight: 0.5},
+		WeightedGuider{Name: "line", Guider: line, Weight: 0.5},
+	)
+	newStates := runEpisodes(t, guider, 5)
+	if newStates[0] == 0 {
+		t.Error("expected the first trace to be interesting")
+	}
+	if n := tlc.Requests(); n != 5 {
+		t.Errorf("expected the guiders to share one request per trace, got %d requests", n)
+	}
+
+	cov := guider.Coverage()
+	if cov.UniqueStates != native.Coverage().UniqueStates || cov.UniqueStates == 0 {
+		t.Errorf("expected the states of the native guider, got %d", cov.UniqueStates)
+	}
+	if cov.UniqueEventTraces == 0 || cov.UniqueEventTraces != trace.Coverage().UniqueEventTraces {
+		t.Errorf("expected the event traces of the trace guider, got %d", cov.UniqueEventTraces)
+	}
+	if cov.CoveredLines != line.Coverage().CoveredLines {
+		t.Errorf("expected the lines of the line guider, got %d", cov.CoveredLines)
+	}
+
+	tlc.DivergeOn("SendMessage")
+	runEpisodes(t, guider, 2)
+	if d := guider.Coverage().Divergences; d != 2 || trace.Coverage().Divergences+line.Coverage().Divergences != 2 {
+		t.Errorf("expected one guider to count the 2 divergences, got %d", d)
+	}
+
+	// Zero weights still make a trace interesting
+	zero := NewMultiGuider(WeightedGuider{Name: "native", Guider: NewNativeStateGuider(DefaultAbstraction(), "", false)})
+	if n := runEpisodes(t, zero, 1); n[0] != 1 {
+		t.Errorf("expected a new trace to be interesting with a zero weight, got %d", n[0])
+	}
+}
+
+func TestMultiGuiderReset(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	recordPath := t.TempDir() + "/record"
+	guider := NewMultiGuider(
+		WeightedGuider{Name: "tlc", Guider: NewTLCStateGuider(tlc.Client(), recordPath, true), Weight: 1},
+		WeightedGuider{Name: "trace", Guider: NewTraceCoverageGuider(tlc.Client(), recordPath, true), Weight: 1},
+	)
+	runEpisodes(t, guider, 2)
+	guider.Reset("multi")
+	for _, name := range []string{"multi_tlc_0.json", "multi_trace_0.json"} {
+		if _, err := os.Stat(path.Join(recordPath, contributionsDir, name)); err != nil {
+			t.Errorf("expected the record of every guider: %s", err)
+		}
+	}
+}
+
+func TestRecordTracesPerGuider(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	recordPath := t.TempDir() + "/record"
+	guider := NewMultiGuider(
+		WeightedGuider{Name: "tlc", Guider: NewTLCStateGuider(tlc.Client(), recordPath, true), Weight: 1},
+		WeightedGuider{Name: "trace", Guider: NewTraceCoverageGuider(tlc.Client(), recordPath, true), Weight: 1},
+	)
+	guider.SetName("multi")
+	runEpisodes(t, guider, 2)
+	for _, name := range []string{"multi_tlc", "multi_trace"} {
+		if _, _, err := readRecordedTrace(path.Join(recordPath, name, "0.json")); err != nil {
+			t.Errorf("expected the traces of every guider: %s", err)
+		}
+	}
+}
+
+func TestParseGuiderWeights(t *testing.T) {
+	weights, err := parseGuiderWeights([]string{"tlc=1", "line=0.5"})
+	if err != nil || weights["tlc"] != 1 || weights["line"] != 0.5 {
+		t.Errorf("unexpected weights %v: %v", weights, err)
+	}
+	for _, invalid := range []string{"tlc", "tlc=x", "tlc=-1"} {
+		if _, err := parseGuiderWeights([]string{invalid}); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+	if _, err := parseGuiderWeights([]string{"tlc=1", "tlc=2"}); err == nil {
+		t.Error("expected a guider given twice to be rejected")
+	}
+}
+
+func TestTLCStateGuiderDivergence(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	recordPath := t.TempDir() + "/record"
+	guider := NewTLCStateGuider(tlc.Client(), recordPath, true)
+	guider.SetName("tlc")
+	runEpisodes(t, guider, 3)
+	if d := guider.Coverage().Divergences; d != 0 {
+		t.Fatalf("expected no divergences, got %d", d)
+	}
+
+	tlc.DivergeOn("SendMessage")
+	runEpisodes(t, guider, 3)
+	if d := guider.Coverage().Divergences; d != 3 {
+		t.Fatalf("expected 3 divergences, got %d", d)
+	}
+	data, err := os.ReadFile(path.Join(recordPath, "divergences", "tlc", "0_1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := struct {
+		Event *Event `json:"unmatched_event"`
+	}{}
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		t.Fatal(err)
+	}
+	if recorded.Event == nil || recorded.Event.Name != "SendMessage" {
+		t.Errorf("expected the unmatched event to be recorded, got %v", recorded.Event)
+	}
+	guider.Reset("divergence")
+	if d := guider.Coverage().Divergences; d != 0 {
+		t.Errorf("expected no divergences after reset, got %d", d)
+	}
+
+	// The divergences are saved even when the traces are not recorded
+	quietPath := t.TempDir() + "/quiet"
+	quiet := NewTLCStateGuider(tlc.Client(), quietPath, false)
+	quiet.SetName("quiet")
+	runEpisodes(t, quiet, 2)
+	if d := quiet.Coverage().Divergences; d != 2 {
+		t.Errorf("expected 2 divergences, got %d", d)
+	}
+	if _, err := os.Stat(path.Join(quietPath, "divergences", "quiet", "0_2.json")); err != nil {
+		t.Errorf("expected the divergences to be saved without recording traces: %s", err)
+	}
+}
+
+func TestTLCStateGuiderStateMismatch(t *testing.T) {
+	guider := NewTLCStateGuider(nil, "", false)
+	events := testEventTrace()
+	guider.checkDivergence(NewList[*SchedulingChoice](), events, make([]State, events.Size()+3))
+	guider.checkDivergence(NewList[*SchedulingChoice](), events, nil)
+	if d := guider.Coverage().Divergences; d != 0 {
+		t.Errorf("expected responses that do not follow the events not to be divergences, got %d", d)
+	}
+	guider.checkDivergence(NewList[*SchedulingChoice](), events, make([]State, 2))
+	if d := guider.Coverage().Divergences; d != 1 {
+		t.Errorf("expected a divergence, got %d", d)
+	}
+}
+
+func TestTLCStateGuiderFallback(t *testing.T) {
+	config := DefaultTLCClientConfig("127.0.0.1:1")
+	config.Retries = 0
+	guider := NewTLCStateGuider(NewTLCClientWithConfig(config), "", false)
+	guider.SetFallback(RolesTermsAbstraction())
+	runEpisodes(t, guider, 3)
+	c := guider.Coverage()
+	if c.FallbackStates == 0 || c.FallbackEpisodes != 3 {
+		t.Errorf("expected the fallback to cover states in 3 episodes, got %+v", c)
+	}
+	if c.UniqueStates != 0 {
+		t.Errorf("expected the abstract states to be kept out of the TLC states, got %d", c.UniqueStates)
+	}
+}
+
+func TestTLCStateGuiderRecovers(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	config := DefaultTLCClientConfig(tlc.Addr())
+	config.Retries = 0
+	guider := NewTLCStateGuider(NewTLCClientWithConfig(config), "", false)
+	guider.SetFallback(RolesTermsAbstraction())
+	tlc.FailNext(1)
+	runEpisodes(t, guider, 3)
+	c := guider.Coverage()
+	if c.FallbackEpisodes != 1 || c.UniqueStates == 0 {
+		t.Errorf("expected one fallback episode and TLC states once it is back, got %+v", c)
+	}
+}
+
+func TestTLCStateGuiderFailedEpisodes(t *testing.T) {
+	config := DefaultTLCClientConfig("127.0.0.1:1")
+	config.Retries = 0
+	guider := NewTLCStateGuider(NewTLCClientWithConfig(config), "", false)
+	if newStates := runEpisodes(t, guider, 2); newStates[0] != 0 || newStates[1] != 0 {
+		t.Errorf("expected no new states, got %v", newStates)
+	}
+	if c := guider.Coverage(); c.FailedEpisodes != 2 || c.UniqueStates != 0 {
+		t.Errorf("expected 2 failed episodes, got %+v", c)
+	}
+}
+
+func TestAbstractions(t *testing.T) {
+	f := NewFuzzer(testFuzzerConfig(nil, &EmptyMutator{}))
+	_, _, states := f.RunIteration("test", nil)
+	full := abstractStates(states, DefaultAbstraction())
+	for _, name := range []string{"roles-terms", "log-commit", "vote-leader", "term-diff"} {
+		a, err := GetAbstraction(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		distinct := make(map[int64]bool)
+		for _, s := range abstractStates(states, a) {
+			distinct[s.Key] = true
+		}
+		fullDistinct := make(map[int64]bool)
+		for _, s := range full {
+			fullDistinct[s.Key] = true
+		}
+		if len(distinct) > len(fullDistinct) {
+			t.Errorf("%s distinguishes more states than the full abstraction", name)
+		}
+	}
+	if _, err := GetAbstraction("unknown"); err == nil {
+		t.Error("expected an error for an unknown abstraction")
+	}
+}
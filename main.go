@@ -1,7 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -14,10 +20,29 @@ var (
 	requests     int
 	numRuns      int
 	recordTraces bool
+	strategy     string
+	delayBound   int
+	pctDepth     int
+	tlcFallback  bool
+	abstraction  string
+	tlcAddrs     string
+	tlcTimeout   time.Duration
+	tlcRetries   int
+	tlcBatch     int
+	trackCov     bool
+	statusAddr   string
 )
 
+// defaultRecordPath is the directory the guiders record their traces to
+const defaultRecordPath = "traces"
+
 func main() {
-	rootCommand := &cobra.Command{}
+	rootCommand := &cobra.Command{
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			_, err := GetAbstraction(abstraction)
+			return err
+		},
+	}
 	rootCommand.PersistentFlags().IntVarP(&episodes, "episodes", "e", 10000, "Number of episodes to run")
 	rootCommand.PersistentFlags().IntVar(&horizon, "horizon", 100, "Horizon of each episode")
 	rootCommand.PersistentFlags().StringVarP(&savePath, "save", "s", "results", "Save the results to the specified path")
@@ -25,18 +50,41 @@ func main() {
 	rootCommand.PersistentFlags().IntVar(&requests, "requests", 1, "Num of initial requests to serve")
 	rootCommand.PersistentFlags().IntVar(&numRuns, "runs", 5, "Number of runs to average over")
 	rootCommand.PersistentFlags().BoolVar(&recordTraces, "record-traces", false, "Record the traces explored")
+	rootCommand.PersistentFlags().StringVar(&strategy, "strategy", "random", "Strategy used when there is no trace to mimic (random, roundrobin, delay, pos, pct)")
+	rootCommand.PersistentFlags().IntVar(&delayBound, "delay-bound", 3, "Number of delays allowed per episode by the delay strategy")
+	rootCommand.PersistentFlags().IntVar(&pctDepth, "pct-depth", 3, "Bug depth d of the pct strategy")
+	rootCommand.PersistentFlags().BoolVar(&tlcFallback, "tlc-fallback", false, "Use native abstract states when the TLC server is unreachable")
+	rootCommand.PersistentFlags().StringVar(&tlcAddrs, "tlc", "127.0.0.1:2023", "Comma separated TLC server addresses")
+	rootCommand.PersistentFlags().DurationVar(&tlcTimeout, "tlc-timeout", 30*time.Second, "Timeout of a request to the TLC server")
+	rootCommand.PersistentFlags().IntVar(&tlcRetries, "tlc-retries", 5, "Number of retries of a failed request to the TLC server")
+	rootCommand.PersistentFlags().IntVar(&tlcBatch, "tlc-batch", 1, "Number of traces sent to the TLC server in one request")
+	rootCommand.PersistentFlags().BoolVar(&trackCov, "track-coverage", false, "Attribute the lines of raft covered to the traces of every guider, needs a binary built with -cover")
+	rootCommand.PersistentFlags().StringVar(&abstraction, "abstraction", "full", "State abstraction of the native guider (full, roles-terms, log-commit, vote-leader, term-diff)")
+	rootCommand.PersistentFlags().StringVar(&statusAddr, "status-addr", "", "Serve the progress of the runs as JSON and HTML on the address, e.g. localhost:8080")
 	rootCommand.AddCommand(FuzzCommand())
 	rootCommand.AddCommand(OneCommand())
 	rootCommand.AddCommand(MeasureCommand())
+	rootCommand.AddCommand(ReplayCommand())
+	rootCommand.AddCommand(ExploreCommand())
+	rootCommand.AddCommand(SchemaCommand())
+	rootCommand.AddCommand(CoverageReportCommand())
+	rootCommand.AddCommand(SweepCommand())
 
 	if err := rootCommand.Execute(); err != nil {
 		fmt.Println(err)
 	}
 }
 
+func newTLCClient() *TLCClient {
+	config := DefaultTLCClientConfig(strings.Split(tlcAddrs, ",")...)
+	config.Timeout = tlcTimeout
+	config.Retries = tlcRetries
+	config.BatchSize = tlcBatch
+	return NewTLCClientWithConfig(config)
+}
+
 func MeasureCommand() *cobra.Command {
 	var tracesPath string
-	var tlcAddr string
 	var outPath string
 
 	cmd := &cobra.Command{
@@ -45,28 +93,112 @@ func MeasureCommand() *cobra.Command {
 			if outPath == "" {
 				outPath = tracesPath
 			}
-			m := NewTLCCoverageMeasurer(tracesPath, outPath, tlcAddr)
+			m := NewTLCCoverageMeasurer(tracesPath, outPath, newTLCClient())
 			if err := m.Measure(); err != nil {
 				fmt.Println(err)
 			}
 		},
 	}
 	cmd.Flags().StringVar(&tracesPath, "traces", "traces", "Path to traces")
-	cmd.Flags().StringVar(&tlcAddr, "tlc", "localhost:2023", "TLC Server address")
 	cmd.Flags().StringVar(&outPath, "out", "", "Output path")
 
 	return cmd
 }
 
+func withTLCFallback[T interface{ SetFallback(StateAbstraction) }](guider T) T {
+	if tlcFallback {
+		a, _ := GetAbstraction(abstraction)
+		guider.SetFallback(a)
+	}
+	return guider
+}
+
+// withCoverageTracking makes the guider track line coverage when asked to
+func withCoverageTracking(guider Guider) Guider {
+	if !trackCov {
+		return guider
+	}
+	switch g := guider.(type) {
+	case *MultiGuider:
+		for _, wg := range g.Guiders {
+			withCoverageTracking(wg.Guider)
+		}
+	case interface{ TrackCoverage() }:
+		g.TrackCoverage()
+	}
+	return guider
+}
+
+func getGuider(name string, tlcClient *TLCClient, recordPath string) (Guider, error) {
+	switch name {
+	case "tlc":
+		return withTLCFallback(NewTLCStateGuider(tlcClient, recordPath, recordTraces)), nil
+	case "trace":
+		return withTLCFallback(NewTraceCoverageGuider(tlcClient, recordPath, recordTraces)), nil
+	case "line":
+		return withTLCFallback(NewLineCoverageGuider(tlcClient, recordPath, recordTraces)), nil
+	case "edge":
+		return withTLCFallback(NewEdgeCoverageGuider(tlcClient, recordPath, recordTraces)), nil
+	case "native":
+		a, err := GetAbstraction(abstraction)
+		if err != nil {
+			return nil, err
+		}
+		return NewNativeStateGuider(a, recordPath, recordTraces), nil
+	}
+	return nil, fmt.Errorf("unknown guider: %s", name)
+}
+
+// getMultiGuider combines the guiders given as name=weight. They share the
+// TLC client, which sends every trace once.
+func getMultiGuider(weights []string, tlcClient *TLCClient, recordPath string) (*MultiGuider, error) {
+	parsed, err := parseGuiderWeights(weights)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no guiders to combine")
+	}
+	guiders := make([]WeightedGuider, 0, len(parsed))
+	for _, w := range weights {
+		name, _, _ := strings.Cut(w, "=")
+		g, err := getGuider(name, tlcClient, recordPath)
+		if err != nil {
+			return nil, err
+		}
+		guiders = append(guiders, WeightedGuider{Name: name, Guider: g, Weight: parsed[name]})
+	}
+	return NewMultiGuider(guiders...), nil
+}
+
 func FuzzCommand() *cobra.Command {
-	return &cobra.Command{
+	var guiderName string
+	var guiderWeights []string
+	cmd := &cobra.Command{
 		Use: "fuzz",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fuzzer := NewFuzzer(&FuzzerConfig{
+			s, err := GetStrategy(strategy, replicas, delayBound, pctDepth, 0)
+			if err != nil {
+				return err
+			}
+			var guider Guider
+			if guiderName == "multi" {
+				guider, err = getMultiGuider(guiderWeights, newTLCClient(), defaultRecordPath)
+			} else {
+				guider, err = getGuider(guiderName, newTLCClient(), defaultRecordPath)
+			}
+			if err != nil {
+				return err
+			}
+			status, err := startStatusServer()
+			if err != nil {
+				return err
+			}
+			config := &FuzzerConfig{
 				Iterations: episodes,
 				Steps:      horizon,
-				Strategy:   NewRandomStrategy(),
-				Guider:     NewLineCoverageGuider("127.0.0.1:2023", "traces", recordTraces),
+				Strategy:   s,
+				Guider:     withCoverageTracking(guider),
 				Mutator:    &EmptyMutator{},
 				RaftEnvironmentConfig: RaftEnvironmentConfig{
 					Replicas:      replicas,
@@ -79,49 +211,399 @@ func FuzzCommand() *cobra.Command {
 				CrashQuota:         2,
 				MaxMessages:        10,
 				SeedPopulationSize: 10,
-			})
+				ReseedFrequency:    200,
+			}
+			if status != nil {
+				config.Observer = status.Observer(guiderName, 0)
+			}
+			setGuiderName(guider, guiderName)
+			fuzzer := NewFuzzer(config)
 			fuzzer.Run()
+			// Writes the coverage record of the run
+			guider.Reset(guiderName)
 			return nil
 		},
 	}
+	cmd.Flags().StringVar(&guiderName, "guider", "line", "Guider to use (tlc, trace, line, edge, native, multi)")
+	cmd.Flags().StringSliceVar(&guiderWeights, "weights", []string{"tlc=1", "trace=1", "line=1"}, "Guiders combined by the multi guider with their weights")
+	return cmd
+}
+
+// compareConfig is the config of the benchmarks of compare and of the
+// settings of sweep
+func compareConfig(s Strategy) *FuzzerConfig {
+	return &FuzzerConfig{
+		Iterations:  episodes,
+		Steps:       horizon,
+		Strategy:    s,
+		Mutator:     &EmptyMutator{},
+		Checker:     SerializabilityChecker(),
+		CheckerName: "serializability",
+		RaftEnvironmentConfig: RaftEnvironmentConfig{
+			Replicas: replicas,
+			// Lower election tick gives random better chances. (more timeouts)
+			ElectionTick:  20,
+			HeartbeatTick: 4,
+			// Should not be more than ElectionTick/(replica+1) otherwise you are more likely to starve processes
+			TicksPerStep: 3,
+		},
+		// Too much is bad, can lead to very local search
+		MutPerTrace:    3,
+		NumberRequests: requests,
+		// More makes random worse
+		CrashQuota: 10,
+		// Too few messages are better for random
+		MaxMessages:        5,
+		SeedPopulationSize: 20,
+		ReseedFrequency:    200,
+	}
 }
 
 func OneCommand() *cobra.Command {
-	return &cobra.Command{
+	var compareStrategies bool
+	var compareAbstractions []string
+	var eventMutators bool
+	var splice bool
+	var mutatorName string
+	var multiWeights []string
+	var edgeCoverage bool
+	var raftSource string
+	var configPath string
+	cmd := &cobra.Command{
 		Use: "compare",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := GetStrategy(strategy, replicas, delayBound, pctDepth, 0)
+			if err != nil {
+				return err
+			}
 
-			c := NewComparision(savePath, &FuzzerConfig{
-				Iterations: episodes,
-				Steps:      horizon,
-				Strategy:   NewRandomStrategy(),
-				Mutator:    &EmptyMutator{},
-				Checker:    SerializabilityChecker(),
+			config := compareConfig(s)
+			tlcClient := newTLCClient()
+			if configPath != "" {
+				experiment, err := readExperimentConfig(configPath)
+				if err != nil {
+					return err
+				}
+				runs := numRuns
+				if experiment.Episodes > 0 {
+					config.Iterations = experiment.Episodes
+				}
+				if experiment.Horizon > 0 {
+					config.Steps = experiment.Horizon
+				}
+				if experiment.Runs > 0 {
+					runs = experiment.Runs
+				}
+				if len(experiment.Seeds) > runs {
+					return fmt.Errorf("%d seeds given for %d runs", len(experiment.Seeds), runs)
+				}
+				c := NewComparision(savePath, config, runs)
+				if err := experiment.addBenchmarks(c, config, tlcClient, defaultRecordPath); err != nil {
+					return err
+				}
+				c.SetSeeds(experiment.Seeds)
+				return runComparision(c, defaultRecordPath, raftSource)
+			}
+			c := NewComparision(savePath, config, numRuns)
+			combinedMutator, err := getCompareMutator(mutatorName, config, 0)
+			if err != nil {
+				return err
+			}
+			c.Add("traceCov", combinedMutator, withTLCFallback(NewTraceCoverageGuider(tlcClient, defaultRecordPath, recordTraces)))
+			c.Add("lineCov", combinedMutator, withTLCFallback(NewLineCoverageGuider(tlcClient, defaultRecordPath, recordTraces)))
+			c.Add("tlcstate", combinedMutator, withTLCFallback(NewTLCStateGuider(tlcClient, defaultRecordPath, recordTraces)))
+			c.Add("random", &EmptyMutator{}, withTLCFallback(NewTLCStateGuider(tlcClient, defaultRecordPath, recordTraces)))
+			for _, name := range compareAbstractions {
+				a, err := GetAbstraction(name)
+				if err != nil {
+					return err
+				}
+				c.Add("native-"+name, combinedMutator, NewNativeStateGuider(a, defaultRecordPath, recordTraces))
+			}
+			if eventMutators {
+				c.Add("tlcstate-events", NewChooseMutator(combinedMutator, NewEventMutator()), withTLCFallback(NewTLCStateGuider(tlcClient, defaultRecordPath, recordTraces)))
+			}
+			if splice {
+				c.Add("tlcstate-splice", NewChooseMutator(combinedMutator, NewSpliceMutator(100)), withTLCFallback(NewTLCStateGuider(tlcClient, defaultRecordPath, recordTraces)))
+			}
+			if edgeCoverage {
+				c.Add("edgeCov", combinedMutator, withTLCFallback(NewEdgeCoverageGuider(tlcClient, defaultRecordPath, recordTraces)))
+			}
+			if len(multiWeights) > 0 {
+				multi, err := getMultiGuider(multiWeights, tlcClient, defaultRecordPath)
+				if err != nil {
+					return err
+				}
+				c.Add("multi", combinedMutator, multi)
+			}
+			if compareStrategies {
+				c.AddWithStrategy("delay", NewDelayBoundedStrategy(delayBound), &EmptyMutator{}, withTLCFallback(NewTLCStateGuider(tlcClient, defaultRecordPath, recordTraces)))
+				c.AddWithStrategy("pos", NewPOSStrategy(), &EmptyMutator{}, withTLCFallback(NewTLCStateGuider(tlcClient, defaultRecordPath, recordTraces)))
+				c.AddWithStrategy("pct", NewPCTStrategy(pctDepth), &EmptyMutator{}, withTLCFallback(NewTLCStateGuider(tlcClient, defaultRecordPath, recordTraces)))
+			}
+
+			return runComparision(c, defaultRecordPath, raftSource)
+		},
+	}
+	cmd.Flags().BoolVar(&compareStrategies, "strategies", false, "Also compare the unguided scheduling strategies")
+	cmd.Flags().StringSliceVar(&compareAbstractions, "abstractions", []string{"full"}, "State abstractions to compare with the native guider")
+	cmd.Flags().StringVar(&mutatorName, "mutator", "combined", "Mutator of the guided benchmarks (none, combined, bandit, events, splice)")
+	cmd.Flags().BoolVar(&splice, "splice", false, "Also compare the tlc state guider with splicing of interesting traces")
+	cmd.Flags().BoolVar(&eventMutators, "event-mutators", false, "Also compare the tlc state guider with the event aware mutators")
+	cmd.Flags().StringVar(&raftSource, "raft-source", "raft", "Source directory of the raft package annotated in the coverage report")
+	cmd.Flags().StringVar(&configPath, "config", "", "JSON file declaring the benchmarks to compare, replaces the benchmark flags")
+	cmd.Flags().BoolVar(&edgeCoverage, "edges", false, "Also compare the guider of the block coverage of raft, needs a binary built with -cover -covermode=atomic")
+	cmd.Flags().StringSliceVar(&multiWeights, "multi", nil, "Also compare a guider combining the given guiders, as name=weight (tlc, trace, line, edge, native)")
+	return cmd
+}
+
+// runComparision runs the benchmarks and writes the coverage report of raft
+// next to the plots when line coverage is tracked and recorded
+func runComparision(c *Comparision, recordPath, raftSource string) error {
+	status, err := startStatusServer()
+	if err != nil {
+		return err
+	}
+	c.SetStatus(status)
+	for _, b := range c.benchmarks {
+		withCoverageTracking(b.guider)
+	}
+	c.Run()
+	if trackCov && !recordTraces {
+		fmt.Println("Not writing the coverage report, the coverage of the guiders is only recorded with --record-traces")
+	} else if trackCov {
+		if err := writeCoverageHTML(path.Join(savePath, "coverage.html"), recordPath, raftSource); err != nil {
+			fmt.Printf("Error writing the coverage report: %s\n", err)
+		}
+	}
+	return nil
+}
+
+// startStatusServer serves the progress of the runs on --status-addr, it
+// returns nil when the flag is not set
+func startStatusServer() (*StatusServer, error) {
+	if statusAddr == "" {
+		return nil, nil
+	}
+	status := NewStatusServer()
+	if err := status.Start(statusAddr); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func ReplayCommand() *cobra.Command {
+	var tracePath string
+
+	cmd := &cobra.Command{
+		Use: "replay",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			trace, env, err := readRecordedTrace(tracePath)
+			if err != nil {
+				return err
+			}
+			if env == nil {
+				fmt.Println("The trace does not record its environment, replaying with the default one")
+				env = &TraceEnvironment{
+					Raft: RaftEnvironmentConfig{
+						Replicas:      replicas,
+						ElectionTick:  20,
+						HeartbeatTick: 4,
+						TicksPerStep:  3,
+					},
+					Steps:       horizon,
+					MaxMessages: 5,
+				}
+			}
+			fuzzer := NewFuzzer(&FuzzerConfig{
+				Iterations:            1,
+				Steps:                 env.Steps,
+				Strategy:              NewRandomStrategy(),
+				Checker:               SerializabilityChecker(),
+				CheckerName:           "serializability",
+				RaftEnvironmentConfig: env.Raft,
+				MaxMessages:           env.MaxMessages,
+			})
+			_, eventTrace, _ := fuzzer.RunIteration("replay", trace)
+			for _, e := range eventTrace.Iter() {
+				fmt.Printf("%s %v\n", e.Name, e.Params)
+			}
+			if fuzzer.stats.IsBuggy("replay") {
+				fmt.Println("Checker failed on the replayed trace")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&tracePath, "trace", "", "Path to a recorded trace")
+	cmd.MarkFlagRequired("trace")
+
+	return cmd
+}
+
+func ExploreCommand() *cobra.Command {
+	var depth int
+	var delays int
+	var maxMessages int
+
+	cmd := &cobra.Command{
+		Use:   "explore",
+		Short: "Enumerate all schedules up to a depth and count the distinct states (no crashes)",
+		Run: func(cmd *cobra.Command, args []string) {
+			e := NewExplorer(&FuzzerConfig{
+				Strategy:    NewRandomStrategy(),
+				Checker:     SerializabilityChecker(),
+				CheckerName: "serializability",
 				RaftEnvironmentConfig: RaftEnvironmentConfig{
-					Replicas: replicas,
-					// Lower election tick gives random better chances. (more timeouts)
+					Replicas:      replicas,
 					ElectionTick:  20,
 					HeartbeatTick: 4,
-					// Should not be more than ElectionTick/(replica+1) otherwise you are more likely to starve processes
-					TicksPerStep: 3,
+					TicksPerStep:  3,
+					Seed:          1,
 				},
-				// Too much is bad, can lead to very local search
-				MutPerTrace:    3,
 				NumberRequests: requests,
-				// More makes random worse
-				CrashQuota: 10,
-				// Too few messages are better for random
-				MaxMessages:        5,
-				SeedPopulationSize: 20,
-				ReseedFrequency:    200,
-			}, numRuns)
-			combinedMutator := CombineMutators(NewSwapCrashNodeMutator(2), NewSwapNodeMutator(20), NewSwapMaxMessagesMutator(20))
-			c.Add("traceCov", combinedMutator, NewTraceCoverageGuider("127.0.0.1:2023", "traces", recordTraces))
-			c.Add("lineCov", combinedMutator, NewLineCoverageGuider("127.0.0.1:2023", "traces", recordTraces))
-			c.Add("tlcstate", combinedMutator, NewTLCStateGuider("127.0.0.1:2023", "traces", recordTraces))
-			c.Add("random", &EmptyMutator{}, NewTLCStateGuider("127.0.0.1:2023", "traces", recordTraces))
-
-			c.Run()
+				MaxMessages:    maxMessages,
+			}, depth, delays, savePath)
+			result := e.Explore()
+			fmt.Printf("Explored %d schedules, %d distinct states (%d violating the checker)\n", result.Executions, result.TotalStates, result.BuggyStates)
 		},
 	}
+	cmd.Flags().IntVar(&depth, "depth", 8, "Maximum number of steps to explore")
+	cmd.Flags().IntVar(&delays, "delays", -1, "Maximum number of deviations from the round robin schedule (-1 for no bound)")
+	cmd.Flags().IntVar(&maxMessages, "max-messages", 5, "Messages delivered by each scheduling choice")
+
+	return cmd
+}
+
+func SchemaCommand() *cobra.Command {
+	var outPath string
+
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON schema of the event traces sent to the TLC server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := json.MarshalIndent(EventJSONSchema(), "", "\t")
+			if err != nil {
+				return err
+			}
+			data = append(data, '\n')
+			if outPath == "" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			return os.WriteFile(outPath, data, 0644)
+		},
+	}
+	cmd.Flags().StringVar(&outPath, "out", "", "Write the schema to the file instead of stdout")
+
+	return cmd
+}
+
+func CoverageReportCommand() *cobra.Command {
+	var tracesPath string
+	var file string
+	var outPath string
+	var htmlPath string
+	var raftSource string
+
+	cmd := &cobra.Command{
+		Use:   "coverage-report",
+		Short: "Show the functions of raft each guider reached from the recorded coverage",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			records, err := readCoverageRecords(tracesPath)
+			if err != nil {
+				return err
+			}
+			if htmlPath != "" {
+				if err := writeCoverageHTML(htmlPath, tracesPath, raftSource); err != nil {
+					return err
+				}
+			}
+			report := renderCoverageReport(records, file)
+			if outPath == "" {
+				fmt.Print(report)
+				return nil
+			}
+			return os.WriteFile(outPath, []byte(report), 0644)
+		},
+	}
+	cmd.Flags().StringVar(&tracesPath, "traces", defaultRecordPath, "Path the guiders recorded to with --record-traces")
+	cmd.Flags().StringVar(&file, "file", "raft.go", "Source file of the raft package to report on, all files when empty")
+	cmd.Flags().StringVar(&outPath, "out", "", "Write the report to the file instead of stdout")
+	cmd.Flags().StringVar(&htmlPath, "html", "", "Also write the annotated sources of raft as HTML to the file")
+	cmd.Flags().StringVar(&raftSource, "raft-source", "raft", "Source directory of the raft package")
+
+	return cmd
+}
+
+func SweepCommand() *cobra.Command {
+	var params []string
+	var search string
+	var samples int
+	var seed int64
+	var guiderName string
+	var mutatorName string
+
+	cmd := &cobra.Command{
+		Use:   "sweep",
+		Short: "Run the fuzzer over a grid of environment and fuzzer parameters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(params) == 0 {
+				return fmt.Errorf("no parameters to sweep, expected --param name=start:end[:step] or name=v1,v2")
+			}
+			sweep := &Sweep{Runs: numRuns}
+			for _, spec := range params {
+				p, err := parseSweepParameter(spec)
+				if err != nil {
+					return err
+				}
+				sweep.Params = append(sweep.Params, p)
+			}
+			switch search {
+			case "grid":
+				sweep.Settings = gridSettings(sweep.Params)
+			case "random":
+				if seed == 0 {
+					seed = time.Now().UnixNano()
+				}
+				sweep.Settings = randomSettings(sweep.Params, samples, rand.New(rand.NewSource(seed)))
+			default:
+				return fmt.Errorf("unknown search: %s", search)
+			}
+			if _, err := GetStrategy(strategy, replicas, delayBound, pctDepth, 0); err != nil {
+				return err
+			}
+			// Every setting gets a fresh strategy
+			sweep.NewConfig = func() *FuzzerConfig {
+				s, _ := GetStrategy(strategy, replicas, delayBound, pctDepth, 0)
+				return compareConfig(s)
+			}
+			sweep.NewMutator = func(config *FuzzerConfig) (Mutator, error) {
+				return getCompareMutator(mutatorName, config, 0)
+			}
+			guider, err := getGuider(guiderName, newTLCClient(), defaultRecordPath)
+			if err != nil {
+				return err
+			}
+			sweep.Guider = withCoverageTracking(guider)
+			if sweep.Status, err = startStatusServer(); err != nil {
+				return err
+			}
+
+			results, err := sweep.Run()
+			if err != nil {
+				return err
+			}
+			sortSweepResults(results)
+			fmt.Printf("\n%s", formatSweepResults(sweep.Params, results))
+			return writeSweepResults(savePath, sweep.Params, results)
+		},
+	}
+	cmd.Flags().StringArrayVar(&params, "param", nil, "Parameter to sweep as name=start:end[:step] or name=v1,v2 ("+strings.Join(sweepParameters, ", ")+")")
+	cmd.Flags().StringVar(&search, "search", "grid", "Search over the settings (grid, random)")
+	cmd.Flags().IntVar(&samples, "samples", 10, "Number of settings drawn by the random search")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "Seed of the random search")
+	cmd.Flags().StringVar(&guiderName, "guider", "tlc", "Guider to use (tlc, trace, line, edge, native)")
+	cmd.Flags().StringVar(&mutatorName, "mutator", "combined", "Mutator to use (none, combined, bandit, events, splice)")
+
+	return cmd
 }
@@ -11,20 +11,18 @@ import (
 
 type TLCCoverageMeasurer struct {
 	tracesPath string
-	tlcAddr    string
 	outPath    string
 
 	tlcClient *TLCClient
 	cov       map[int64]int
 }
 
-func NewTLCCoverageMeasurer(tracesPath, outPath, tlcAddr string) *TLCCoverageMeasurer {
+func NewTLCCoverageMeasurer(tracesPath, outPath string, tlcClient *TLCClient) *TLCCoverageMeasurer {
 	return &TLCCoverageMeasurer{
 		tracesPath: tracesPath,
-		tlcAddr:    tlcAddr,
 		outPath:    outPath,
 
-		tlcClient: NewTLCClient(tlcAddr),
+		tlcClient: tlcClient,
 		cov:       make(map[int64]int),
 	}
 }
@@ -48,21 +46,28 @@ func (p *TLCCoverageMeasurer) Measure() error {
 	}
 	coverages := make([]int, 0)
 	coverages = append(coverages, 0)
-	for i := 1; i < tracePathCount; i++ {
-		tracePath := path.Join(p.tracesPath, fmt.Sprintf("trace_%d.json", i))
-		fmt.Printf("\rChecking %d/%d trace", i, tracePathCount)
-		trace, err := p.parseTrace(tracePath)
-		if err != nil {
-			return fmt.Errorf("error parsing trace: %s", err)
+	batchSize := p.tlcClient.config.BatchSize
+	for i := 1; i < tracePathCount; i += batchSize {
+		traces := make([]*List[*Event], 0, batchSize)
+		for j := i; j < min(i+batchSize, tracePathCount); j++ {
+			tracePath := path.Join(p.tracesPath, fmt.Sprintf("trace_%d.json", j))
+			fmt.Printf("\rChecking %d/%d trace", j, tracePathCount)
+			trace, err := p.parseTrace(tracePath)
+			if err != nil {
+				return fmt.Errorf("error parsing trace: %s", err)
+			}
+			traces = append(traces, trace)
 		}
-		states, err := p.tlcClient.SendTrace(trace)
+		batchStates, err := p.tlcClient.SendTraces(traces)
 		if err != nil {
 			return fmt.Errorf("error sending trace to tlc: %s", err)
 		}
-		for _, state := range states {
-			p.cov[state.Key]++
+		for _, states := range batchStates {
+			for _, state := range states {
+				p.cov[state.Key]++
+			}
+			coverages = append(coverages, len(p.cov))
 		}
-		coverages = append(coverages, len(p.cov))
 	}
 	fmt.Println("... Done")
 	jsonData, err := json.Marshal(map[string]interface{}{
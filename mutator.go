@@ -27,6 +27,10 @@ func NewChoiceMutator(flips int) *ChoiceMutator {
 	}
 }
 
+func (c *ChoiceMutator) Seed(seed int64) {
+	c.rand = rand.New(rand.NewSource(seed))
+}
+
 var _ Mutator = &ChoiceMutator{}
 
 func (c *ChoiceMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
@@ -50,14 +54,11 @@ func (c *ChoiceMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event])
 
 	newTrace := NewList[*SchedulingChoice]()
 	for i, choice := range trace.Iter() {
+		newChoice := choice.Copy()
 		if _, ok := toFlip[i]; ok {
-			newTrace.Append(&SchedulingChoice{
-				Type:          choice.Type,
-				BooleanChoice: !choice.BooleanChoice,
-			})
-		} else {
-			newTrace.Append(choice.Copy())
+			newChoice.BooleanChoice = !choice.BooleanChoice
 		}
+		newTrace.Append(newChoice)
 	}
 
 	return newTrace, true
@@ -77,6 +78,10 @@ func NewSkipNodeMutator(skips int) *SkipNodeMutator {
 	}
 }
 
+func (d *SkipNodeMutator) Seed(seed int64) {
+	d.rand = rand.New(rand.NewSource(seed))
+}
+
 func (d *SkipNodeMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
 	nodeChoiceIndices := make([]int, 0)
 	for i, choice := range trace.Iter() {
@@ -118,6 +123,10 @@ func NewSwapNodeMutator(swaps int) *SwapNodeMutator {
 	}
 }
 
+func (s *SwapNodeMutator) Seed(seed int64) {
+	s.rand = rand.New(rand.NewSource(seed))
+}
+
 func (s *SwapNodeMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
 	nodeChoiceIndices := make([]int, 0)
 	for i, choice := range trace.Iter() {
@@ -166,6 +175,10 @@ func NewSwapIntegerChoiceMutator(numswaps int) *SwapIntegerChoiceMutator {
 	}
 }
 
+func (s *SwapIntegerChoiceMutator) Seed(seed int64) {
+	s.rand = rand.New(rand.NewSource(seed))
+}
+
 func (s *SwapIntegerChoiceMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
 	integerChoiceIndices := make([]int, 0)
 	for i, choice := range trace.Iter() {
@@ -211,6 +224,10 @@ func NewScaleDownIntChoiceMutator(numPoints int) *ScaleDownIntChoiceMutator {
 	}
 }
 
+func (s *ScaleDownIntChoiceMutator) Seed(seed int64) {
+	s.rand = rand.New(rand.NewSource(seed))
+}
+
 func (s *ScaleDownIntChoiceMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
 	integerChoiceIndices := make([]int, 0)
 	for i, choice := range trace.Iter() {
@@ -262,6 +279,10 @@ func NewScaleUpIntChoiceMutator(numPoints, max int) *ScaleUpIntChoiceMutator {
 	}
 }
 
+func (s *ScaleUpIntChoiceMutator) Seed(seed int64) {
+	s.rand = rand.New(rand.NewSource(seed))
+}
+
 func (s *ScaleUpIntChoiceMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
 	integerChoiceIndices := make([]int, 0)
 	for i, choice := range trace.Iter() {
@@ -331,6 +352,32 @@ func (c *combinedMutator) Mutate(trace *List[*SchedulingChoice], eventTrace *Lis
 	return curTrace, true
 }
 
+func (c *combinedMutator) Reset() {
+	for _, m := range c.mutators {
+		resetMutator(m)
+	}
+}
+
+func (c *combinedMutator) Feedback(trace *List[*SchedulingChoice], newStates int) {
+	for _, m := range c.mutators {
+		mutatorFeedback(m, trace, newStates)
+	}
+}
+
+func (c *combinedMutator) Discard() {
+	for _, m := range c.mutators {
+		discardFeedback(m)
+	}
+}
+
+// Seed seeds every mutator with a seed drawn from the seed
+func (c *combinedMutator) Seed(seed int64) {
+	r := rand.New(rand.NewSource(seed))
+	for _, m := range c.mutators {
+		seedRandom(m, r.Int63())
+	}
+}
+
 func CombineMutators(mutators ...Mutator) Mutator {
 	return &combinedMutator{
 		mutators: mutators,
@@ -351,6 +398,10 @@ func NewSwapCrashNodeMutator(swaps int) *SwapCrashNodeMutator {
 	}
 }
 
+func (s *SwapCrashNodeMutator) Seed(seed int64) {
+	s.r = rand.New(rand.NewSource(seed))
+}
+
 func (s *SwapCrashNodeMutator) Mutate(trace *List[*SchedulingChoice], eventTrace *List[*Event]) (*List[*SchedulingChoice], bool) {
 	swaps := make(map[int]int)
 
@@ -400,6 +451,10 @@ func NewSwapMaxMessagesMutator(swaps int) *SwapMaxMessagesMutator {
 	}
 }
 
+func (s *SwapMaxMessagesMutator) Seed(seed int64) {
+	s.r = rand.New(rand.NewSource(seed))
+}
+
 func (s *SwapMaxMessagesMutator) Mutate(trace *List[*SchedulingChoice], eventTrace *List[*Event]) (*List[*SchedulingChoice], bool) {
 	swaps := make(map[int]int)
 
@@ -434,3 +489,109 @@ func (s *SwapMaxMessagesMutator) Mutate(trace *List[*SchedulingChoice], eventTra
 	}
 	return newTrace, true
 }
+
+// SpliceMutator crosses the trace with another interesting trace. It keeps a
+// bounded corpus of the traces it was asked to mutate and splices a prefix of
+// the trace with the suffix of a trace from the corpus at a random step.
+type SpliceMutator struct {
+	CorpusSize int
+	corpus     []*List[*SchedulingChoice]
+	r          *rand.Rand
+}
+
+var _ Mutator = &SpliceMutator{}
+
+func NewSpliceMutator(corpusSize int) *SpliceMutator {
+	return &SpliceMutator{
+		CorpusSize: corpusSize,
+		corpus:     make([]*List[*SchedulingChoice], 0),
+		r:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (s *SpliceMutator) Seed(seed int64) {
+	s.r = rand.New(rand.NewSource(seed))
+}
+
+func (s *SpliceMutator) Reset() {
+	s.corpus = make([]*List[*SchedulingChoice], 0)
+}
+
+func (s *SpliceMutator) addToCorpus(trace *List[*SchedulingChoice]) {
+	for _, t := range s.corpus {
+		if t == trace {
+			return
+		}
+	}
+	if len(s.corpus) < s.CorpusSize {
+		s.corpus = append(s.corpus, trace)
+		return
+	}
+	s.corpus[s.r.Intn(len(s.corpus))] = trace
+}
+
+func (s *SpliceMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
+	s.addToCorpus(trace)
+	others := make([]*List[*SchedulingChoice], 0, len(s.corpus))
+	for _, t := range s.corpus {
+		if t != trace {
+			others = append(others, t)
+		}
+	}
+	if len(others) == 0 {
+		return nil, false
+	}
+	other := others[s.r.Intn(len(others))]
+	steps := min(len(nodeChoiceIndices(trace)), len(nodeChoiceIndices(other)))
+	if steps < 2 {
+		return nil, false
+	}
+	return spliceTraces(trace, other, 1+s.r.Intn(steps-1)), true
+}
+
+// spliceTraces takes the choices of the steps before step from the prefix
+// trace and the rest from the suffix trace. Clashing request numbers are
+// renumbered.
+func spliceTraces(prefix, suffix *List[*SchedulingChoice], step int) *List[*SchedulingChoice] {
+	newTrace := NewList[*SchedulingChoice]()
+	appendPart := func(trace *List[*SchedulingChoice], before bool) {
+		nodeChoices := 0
+		for _, ch := range trace.Iter() {
+			if ch.Type == Node {
+				nodeChoices++
+			}
+			// Random choices are made during the step of the last node choice
+			inPrefix := nodeChoices <= step
+			if isStepChoice(ch) {
+				inPrefix = ch.Step < step
+			}
+			if inPrefix == before {
+				newTrace.Append(ch.Copy())
+			}
+		}
+	}
+	appendPart(prefix, true)
+	appendPart(suffix, false)
+
+	newTrace = dropIneffectiveCrashes(newTrace)
+	maxRequest := 0
+	for _, ch := range newTrace.Iter() {
+		if ch.Type == ClientRequest {
+			maxRequest = max(maxRequest, ch.Request)
+		}
+	}
+
+	result := NewList[*SchedulingChoice]()
+	requests := make(map[int]bool)
+	for _, ch := range newTrace.Iter() {
+		if ch.Type == ClientRequest {
+			if requests[ch.Request] {
+				maxRequest++
+				ch.Request = maxRequest
+			}
+			requests[ch.Request] = true
+		}
+		result.Append(ch)
+	}
+	return result
+}
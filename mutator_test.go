@@ -0,0 +1,286 @@
+package main
+
+import (
+	"testing"
+)
+
+func testTrace(t *testing.T) (*List[*SchedulingChoice], *List[*Event]) {
+	t.Helper()
+	f := NewFuzzer(testFuzzerConfig(nil, &EmptyMutator{}))
+	trace, events, _ := f.RunIteration("test", nil)
+	for i := 0; i < 5; i++ {
+		trace.Append(&SchedulingChoice{Type: RandomBoolean, BooleanChoice: i%2 == 0})
+		trace.Append(&SchedulingChoice{Type: RandomInteger, IntegerChoice: i + 1})
+	}
+	return trace, events
+}
+
+func TestMutators(t *testing.T) {
+	mutators := map[string]Mutator{
+		"choice":         NewChoiceMutator(2),
+		"skipNode":       NewSkipNodeMutator(3),
+		"swapNode":       NewSwapNodeMutator(5),
+		"swapInteger":    NewSwapIntegerChoiceMutator(2),
+		"scaleDownInt":   NewScaleDownIntChoiceMutator(2),
+		"scaleUpInt":     NewScaleUpIntChoiceMutator(2, 10),
+		"swapCrashNode":  NewSwapCrashNodeMutator(1),
+		"swapMaxMessage": NewSwapMaxMessagesMutator(5),
+		"combined":       CombineMutators(NewSwapCrashNodeMutator(1), NewSwapNodeMutator(5), NewSwapMaxMessagesMutator(5)),
+	}
+	for name, m := range mutators {
+		t.Run(name, func(t *testing.T) {
+			trace, events := testTrace(t)
+			before := copyTrace(trace, defaultCopyFilter())
+			mutated, ok := m.Mutate(trace, events)
+			if !ok {
+				t.Fatal("expected the mutation to succeed")
+			}
+			if mutated == trace {
+				t.Fatal("expected a new trace")
+			}
+			for i, ch := range trace.Iter() {
+				orig, _ := before.Get(i)
+				if *ch != *orig {
+					t.Fatalf("mutator changed the parent trace at %d", i)
+				}
+			}
+			expected := countChoices(trace, Node)
+			if name == "skipNode" {
+				expected -= 3
+			}
+			if n := countChoices(mutated, Node); n != expected {
+				t.Errorf("expected %d node choices, got %d", expected, n)
+			}
+
+			f := NewFuzzer(testFuzzerConfig(nil, &EmptyMutator{}))
+			f.RunIteration("mutated", mutated)
+		})
+	}
+}
+
+func TestEmptyMutator(t *testing.T) {
+	trace, events := testTrace(t)
+	if _, ok := (&EmptyMutator{}).Mutate(trace, events); ok {
+		t.Error("expected the empty mutator to produce nothing")
+	}
+}
+
+func TestMutatorsWithoutChoices(t *testing.T) {
+	trace := NewList[*SchedulingChoice]()
+	trace.Append(&SchedulingChoice{Type: Node, From: 1, To: 2, MaxMessages: 1})
+	for name, m := range map[string]Mutator{
+		"choice":        NewChoiceMutator(1),
+		"swapInteger":   NewSwapIntegerChoiceMutator(1),
+		"swapCrashNode": NewSwapCrashNodeMutator(1),
+	} {
+		if _, ok := m.Mutate(trace, NewList[*Event]()); ok {
+			t.Errorf("%s: expected no mutation without matching choices", name)
+		}
+	}
+}
+
+func TestEventSteps(t *testing.T) {
+	f := NewFuzzer(testFuzzerConfig(nil, &EmptyMutator{}))
+	trace, events, _ := f.RunIteration("steps", nil)
+	last := 0
+	for _, e := range events.Iter() {
+		if e.Step < last || e.Step >= 50 {
+			t.Fatalf("unexpected step %d of %s after step %d", e.Step, e.Name, last)
+		}
+		last = e.Step
+	}
+
+	// Client requests have to be replayed at the step they were made
+	replayed, _, _ := f.RunIteration("replay", trace)
+	for i, ch := range trace.Iter() {
+		if ch.Type != ClientRequest {
+			continue
+		}
+		r, _ := replayed.Get(i)
+		if r.Type != ClientRequest || r.Step != ch.Step {
+			t.Errorf("expected the client request at step %d to be replayed, got %v", ch.Step, r)
+		}
+	}
+}
+
+// eventTestConfig has longer episodes without crashes so that entries get
+// committed now and then
+func eventTestConfig() *FuzzerConfig {
+	config := testFuzzerConfig(nil, &EmptyMutator{})
+	config.Steps = 100
+	config.CrashQuota = 0
+	return config
+}
+
+// mutateUntil runs episodes until the mutator finds a point of the trace to
+// mutate
+func mutateUntil(t *testing.T, m Mutator) (*List[*SchedulingChoice], *List[*Event], *List[*SchedulingChoice]) {
+	t.Helper()
+	f := NewFuzzer(eventTestConfig())
+	for i := 0; i < 5000; i++ {
+		trace, events, _ := f.RunIteration("event", nil)
+		if mutated, ok := m.Mutate(trace, events); ok {
+			return trace, events, mutated
+		}
+	}
+	t.Fatal("expected the mutation to succeed")
+	return nil, nil, nil
+}
+
+func TestEventMutators(t *testing.T) {
+	t.Run("crashLeader", func(t *testing.T) {
+		_, events, mutated := mutateUntil(t, NewCrashLeaderMutator(3))
+		found := false
+		for _, ch := range mutated.Iter() {
+			if ch.Type != StopNode {
+				continue
+			}
+			for _, e := range events.Iter() {
+				if e.Name == "BecomeLeader" && e.Node == ch.Node && e.Step+1 == ch.Step {
+					found = true
+				}
+			}
+		}
+		if !found {
+			t.Error("expected a crash of a leader right after its election")
+		}
+	})
+	t.Run("delayVoteResponse", func(t *testing.T) {
+		trace, _, mutated := mutateUntil(t, NewDelayVoteResponseMutator(3))
+		if countChoices(mutated, Node) != countChoices(trace, Node) {
+			t.Error("expected the number of node choices to be unchanged")
+		}
+		changed := 0
+		for i, ch := range trace.Iter() {
+			m, _ := mutated.Get(i)
+			if *m != *ch {
+				changed++
+			}
+		}
+		if changed == 0 {
+			t.Error("expected the node choices to be reordered")
+		}
+	})
+	t.Run("duplicateAppend", func(t *testing.T) {
+		_, _, mutated := mutateUntil(t, NewDuplicateAppendMutator())
+		if countChoices(mutated, DuplicateMessage) != 1 {
+			t.Fatal("expected a duplicate message choice")
+		}
+		f := NewFuzzer(eventTestConfig())
+		replayed, events, _ := f.RunIteration("replay", mutated)
+		if countChoices(replayed, DuplicateMessage) != 1 {
+			t.Fatal("expected the duplicate to be replayed")
+		}
+		var duplicate *SchedulingChoice
+		for _, ch := range replayed.Iter() {
+			if ch.Type == DuplicateMessage {
+				duplicate = ch
+			}
+		}
+		// Once an append was delivered on the channel, the duplicate is the
+		// last message delivered on it in the step
+		var appended bool
+		var last *Event
+		for _, e := range events.Iter() {
+			from, _ := eventParamUint(e, "from")
+			if e.Name != string(DeliverMessageEvent) || e.Node != duplicate.To || from != duplicate.From || e.Step > duplicate.Step {
+				continue
+			}
+			appended = appended || e.Params["type"] == "MsgApp"
+			if e.Step == duplicate.Step {
+				last = e
+			}
+		}
+		if appended && (last == nil || last.Params["type"] != "MsgApp") {
+			t.Errorf("expected the last append to be duplicated, got %v", last)
+		}
+	})
+	t.Run("clientRequestTiming", func(t *testing.T) {
+		trace, _, mutated := mutateUntil(t, NewClientRequestTimingMutator())
+		if countChoices(mutated, ClientRequest) != countChoices(trace, ClientRequest) {
+			t.Error("expected the number of client requests to be unchanged")
+		}
+	})
+	t.Run("events", func(t *testing.T) {
+		_, _, mutated := mutateUntil(t, NewEventMutator())
+		f := NewFuzzer(eventTestConfig())
+		f.RunIteration("replay", mutated)
+	})
+}
+
+func TestChooseMutatorLastMutation(t *testing.T) {
+	tag := &tagMutator{}
+	c := NewChooseMutator(tag)
+	if _, ok := c.Mutate(NewList[*SchedulingChoice](), NewList[*Event]()); !ok || mutatorName(c) != "tagMutator" {
+		t.Fatalf("expected the mutation to be reported under the mutator applied, got %s", mutatorName(c))
+	}
+	tag.fail = true
+	if _, ok := c.Mutate(NewList[*SchedulingChoice](), NewList[*Event]()); ok || mutatorName(c) != "choose" {
+		t.Errorf("expected a failed mutation to be reported as choose, got %s", mutatorName(c))
+	}
+}
+
+func TestSpliceTraces(t *testing.T) {
+	prefix := NewList[*SchedulingChoice]()
+	suffix := NewList[*SchedulingChoice]()
+	for i := 0; i < 4; i++ {
+		prefix.Append(&SchedulingChoice{Type: Node, From: 1, To: 2, MaxMessages: 1})
+		suffix.Append(&SchedulingChoice{Type: Node, From: 2, To: 3, MaxMessages: 1})
+	}
+	prefix.Append(&SchedulingChoice{Type: StopNode, Node: 1, Step: 1})
+	prefix.Append(&SchedulingChoice{Type: ClientRequest, Request: 1, Step: 0})
+	prefix.Append(&SchedulingChoice{Type: StartNode, Node: 1, Step: 3})
+	suffix.Append(&SchedulingChoice{Type: StartNode, Node: 2, Step: 2})
+	suffix.Append(&SchedulingChoice{Type: StopNode, Node: 1, Step: 3})
+	suffix.Append(&SchedulingChoice{Type: ClientRequest, Request: 1, Step: 2})
+
+	spliced := spliceTraces(prefix, suffix, 2)
+	nodes := make([]uint64, 0)
+	for _, ch := range spliced.Iter() {
+		switch ch.Type {
+		case Node:
+			nodes = append(nodes, ch.From)
+		case StartNode:
+			t.Errorf("expected the start of a running node to be dropped: %v", ch)
+		case StopNode:
+			if ch.Step != 1 {
+				t.Errorf("expected the stop of a stopped node to be dropped: %v", ch)
+			}
+		case ClientRequest:
+			if ch.Step == 2 && ch.Request != 2 {
+				t.Errorf("expected the clashing request to be renumbered, got %d", ch.Request)
+			}
+		}
+	}
+	if len(nodes) != 4 || nodes[0] != 1 || nodes[1] != 1 || nodes[2] != 2 || nodes[3] != 2 {
+		t.Errorf("expected two node choices of each trace, got %v", nodes)
+	}
+	if countChoices(prefix, ClientRequest) != 1 || countChoices(suffix, StopNode) != 1 {
+		t.Error("expected the parents to be unchanged")
+	}
+}
+
+func TestSpliceMutator(t *testing.T) {
+	m := NewSpliceMutator(2)
+	first, events := testTrace(t)
+	if _, ok := m.Mutate(first, events); ok {
+		t.Fatal("expected no splice with a single trace in the corpus")
+	}
+	second, events := testTrace(t)
+	spliced, ok := m.Mutate(second, events)
+	if !ok {
+		t.Fatal("expected a splice of the two traces")
+	}
+	f := NewFuzzer(testFuzzerConfig(nil, &EmptyMutator{}))
+	f.RunIteration("spliced", spliced)
+
+	third, events := testTrace(t)
+	m.Mutate(third, events)
+	if len(m.corpus) != 2 {
+		t.Errorf("expected the corpus to be bounded, got %d traces", len(m.corpus))
+	}
+	m.Reset()
+	if _, ok := m.Mutate(first, events); ok {
+		t.Error("expected the corpus to be empty after a reset")
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"path"
+	"sort"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// plotMetric is a metric of CoverageStats plotted over the runs
+type plotMetric struct {
+	Name  string
+	Label string
+	Value func(CoverageStats) float64
+	// Optional metrics are plotted only when some benchmark measured them
+	Optional bool
+}
+
+var coverageMetrics = []plotMetric{
+	{Name: "unique_states", Label: "States covered", Value: func(c CoverageStats) float64 { return float64(c.UniqueStates) }},
+	{Name: "unique_traces", Label: "Unique traces", Value: func(c CoverageStats) float64 { return float64(c.UniqueTraces) }},
+	{Name: "unique_state_traces", Label: "Unique state traces", Value: func(c CoverageStats) float64 { return float64(c.UniqueStateTraces) }},
+	{Name: "covered_lines", Label: "Lines of raft covered", Value: func(c CoverageStats) float64 { return float64(c.CoveredLines) }, Optional: true},
+	{Name: "covered_edges", Label: "Blocks of raft covered", Value: func(c CoverageStats) float64 { return float64(c.CoveredEdges) }, Optional: true},
+}
+
+// band is the mean of a series over the runs and the 95% confidence
+// interval of the mean at every point
+type band struct {
+	X    []float64
+	Mean []float64
+	Low  []float64
+	High []float64
+}
+
+// aggregateRuns computes the band of the runs, cut to the shortest run
+func aggregateRuns(runs [][]float64) band {
+	length := -1
+	for _, r := range runs {
+		if length == -1 || len(r) < length {
+			length = len(r)
+		}
+	}
+	b := band{}
+	for i := 0; i < length; i++ {
+		values := make([]float64, len(runs))
+		for j, r := range runs {
+			values[j] = r[i]
+		}
+		s := summarize(values)
+		b.X = append(b.X, float64(i))
+		b.Mean = append(b.Mean, s.Mean)
+		b.Low = append(b.Low, s.CILow)
+		b.High = append(b.High, s.CIHigh)
+	}
+	return b
+}
+
+// cumulativeBugs counts the buggy executions up to every iteration
+func cumulativeBugs(records []BugRecord, iterations int) []int {
+	counts := make([]int, iterations)
+	for _, r := range records {
+		if r.Iteration >= 0 && r.Iteration < iterations {
+			counts[r.Iteration]++
+		}
+	}
+	for i := 1; i < iterations; i++ {
+		counts[i] += counts[i-1]
+	}
+	return counts
+}
+
+// cumulativeSignatures counts the distinct bug signatures found up to every
+// iteration
+func cumulativeSignatures(records []BugRecord, iterations int) []int {
+	counts := make([]int, iterations)
+	seen := make(map[string]bool)
+	for _, r := range records {
+		if !seen[r.Signature] && r.Iteration >= 0 && r.Iteration < iterations {
+			seen[r.Signature] = true
+			counts[r.Iteration]++
+		}
+	}
+	for i := 1; i < iterations; i++ {
+		counts[i] += counts[i-1]
+	}
+	return counts
+}
+
+func fillColor(k int) color.Color {
+	r, g, b, _ := plotutil.Color(k).RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 60}
+}
+
+// plotBands draws the mean of every benchmark as a line over its confidence
+// band and saves the plot as PNG and SVG
+func plotBands(title, xLabel, yLabel string, bands map[string]band, filePath string) error {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = xLabel
+	p.Y.Label.Text = yLabel
+	p.Legend.Top = true
+	p.Legend.Left = true
+
+	names := make([]string, 0, len(bands))
+	for name := range bands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for k, name := range names {
+		b := bands[name]
+		if len(b.X) == 0 {
+			continue
+		}
+		mean := make(plotter.XYs, len(b.X))
+		outline := make(plotter.XYs, 0, 2*len(b.X))
+		for i, x := range b.X {
+			mean[i] = plotter.XY{X: x, Y: b.Mean[i]}
+			outline = append(outline, plotter.XY{X: x, Y: b.High[i]})
+		}
+		for i := len(b.X) - 1; i >= 0; i-- {
+			outline = append(outline, plotter.XY{X: b.X[i], Y: b.Low[i]})
+		}
+		polygon, err := plotter.NewPolygon(outline)
+		if err != nil {
+			return err
+		}
+		polygon.Color = fillColor(k)
+		polygon.LineStyle.Width = 0
+		line, err := plotter.NewLine(mean)
+		if err != nil {
+			return err
+		}
+		line.Color = plotutil.Color(k)
+		p.Add(polygon, line)
+		p.Legend.Add(name, line)
+	}
+	for _, ext := range []string{".png", ".svg"} {
+		if err := p.Save(6*vg.Inch, 4*vg.Inch, filePath+ext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plotAggregates plots every metric over the runs against the iterations,
+// the states covered against the wall-clock time and the bugs found
+func (c *Comparision) plotAggregates() {
+	names := make(map[string]bool)
+	for _, rI := range c.runInfos {
+		for name := range rI.coverages {
+			names[name] = true
+		}
+	}
+	runsOf := func(name string, series func(runInfo) []float64) [][]float64 {
+		runs := make([][]float64, 0, len(c.runInfos))
+		for _, rI := range c.runInfos {
+			if _, ok := rI.coverages[name]; ok {
+				runs = append(runs, series(rI))
+			}
+		}
+		return runs
+	}
+
+	for _, metric := range coverageMetrics {
+		bands := make(map[string]band)
+		measured := false
+		for name := range names {
+			runs := runsOf(name, func(rI runInfo) []float64 {
+				values := make([]float64, len(rI.coverages[name]))
+				for i, cov := range rI.coverages[name] {
+					values[i] = metric.Value(cov)
+					measured = measured || values[i] > 0
+				}
+				return values
+			})
+			bands[name] = aggregateRuns(runs)
+		}
+		if metric.Optional && !measured {
+			continue
+		}
+		if err := plotBands(metric.Label, "Iteration", metric.Label, bands, path.Join(c.plotPath, metric.Name)); err != nil {
+			fmt.Printf("Error plotting %s: %s\n", metric.Name, err)
+		}
+	}
+
+	timeBands := make(map[string]band)
+	bugBands := make(map[string]band)
+	signatureBands := make(map[string]band)
+	for name := range names {
+		states := aggregateRuns(runsOf(name, func(rI runInfo) []float64 {
+			values := make([]float64, len(rI.coverages[name]))
+			for i, cov := range rI.coverages[name] {
+				values[i] = float64(cov.UniqueStates)
+			}
+			return values
+		}))
+		// The states are plotted against the average time of the iterations
+		elapsed := aggregateRuns(runsOf(name, func(rI runInfo) []float64 {
+			values := make([]float64, len(rI.elapsed[name]))
+			for i, d := range rI.elapsed[name] {
+				values[i] = d.Seconds()
+			}
+			return values
+		}))
+		length := len(states.X)
+		if len(elapsed.Mean) < length {
+			length = len(elapsed.Mean)
+		}
+		timeBands[name] = band{X: elapsed.Mean[:length], Mean: states.Mean[:length], Low: states.Low[:length], High: states.High[:length]}
+
+		bugBands[name] = aggregateRuns(runsOf(name, func(rI runInfo) []float64 {
+			return intsToFloats(rI.bugs[name])
+		}))
+		signatureBands[name] = aggregateRuns(runsOf(name, func(rI runInfo) []float64 {
+			return intsToFloats(rI.signatures[name])
+		}))
+	}
+	if err := plotBands("States covered", "Time (s)", "States covered", timeBands, path.Join(c.plotPath, "unique_states_time")); err != nil {
+		fmt.Printf("Error plotting unique_states_time: %s\n", err)
+	}
+	if err := plotBands("Bugs found", "Iteration", "Buggy executions", bugBands, path.Join(c.plotPath, "bugs")); err != nil {
+		fmt.Printf("Error plotting bugs: %s\n", err)
+	}
+	if err := plotBands("Unique bugs found", "Iteration", "Bug signatures", signatureBands, path.Join(c.plotPath, "bug_signatures")); err != nil {
+		fmt.Printf("Error plotting bug_signatures: %s\n", err)
+	}
+}
+
+func intsToFloats(xs []int) []float64 {
+	fs := make([]float64, len(xs))
+	for i, x := range xs {
+		fs[i] = float64(x)
+	}
+	return fs
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAggregateRuns(t *testing.T) {
+	b := aggregateRuns([][]float64{{1, 2, 3}, {3, 4}})
+	if !reflect.DeepEqual(b.X, []float64{0, 1}) || !reflect.DeepEqual(b.Mean, []float64{2, 3}) {
+		t.Fatalf("expected the mean cut to the shortest run: %+v", b)
+	}
+	for i := range b.X {
+		if b.Low[i] >= b.Mean[i] || b.High[i] <= b.Mean[i] {
+			t.Errorf("expected the band around the mean at %d: %+v", i, b)
+		}
+	}
+}
+
+func TestCumulativeBugs(t *testing.T) {
+	records := []BugRecord{{Iteration: 1, Signature: "a"}, {Iteration: 3, Signature: "a"}, {Iteration: 4, Signature: "b"}, {Iteration: 9, Signature: "c"}}
+	if counts := cumulativeBugs(records, 5); !reflect.DeepEqual(counts, []int{0, 1, 1, 2, 3}) {
+		t.Errorf("unexpected cumulative bugs: %v", counts)
+	}
+	if counts := cumulativeSignatures(records, 5); !reflect.DeepEqual(counts, []int{0, 1, 1, 1, 2}) {
+		t.Errorf("unexpected cumulative signatures: %v", counts)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"strconv"
 
 	"github.com/zeu5/raft-fuzzing/raft"
@@ -46,6 +47,8 @@ type RaftEnvironmentConfig struct {
 	ElectionTick  int
 	HeartbeatTick int
 	TicksPerStep  int
+	// Seed makes the election timeouts of the nodes deterministic when non-zero
+	Seed int64
 }
 
 type RaftEnvironment struct {
@@ -82,7 +85,7 @@ func (r *RaftEnvironment) makeNodes() {
 			Storage:                   storage,
 			MaxSizePerMsg:             1024 * 1024,
 			MaxInflightMsgs:           256,
-			Rand:                      nil,
+			Rand:                      r.newRand(nodeID),
 			MaxUncommittedEntriesSize: 1 << 30,
 			Logger:                    &raft.DefaultLogger{Logger: log.New(io.Discard, "", 0)},
 			CheckQuorum:               true,
@@ -95,6 +98,13 @@ func (r *RaftEnvironment) makeNodes() {
 	}
 }
 
+func (r *RaftEnvironment) newRand(nodeID uint64) raft.Rand {
+	if r.config.Seed == 0 {
+		return nil
+	}
+	return rand.New(rand.NewSource(r.config.Seed + int64(nodeID)))
+}
+
 func (r *RaftEnvironment) Reset(ctx *FuzzContext) {
 	r.makeNodes()
 }
@@ -119,14 +129,7 @@ func (r *RaftEnvironment) Step(ctx *FuzzContext, m pb.Message) {
 		if haveLeader {
 			m.To = leader
 			request, _ := strconv.Atoi(string(m.Entries[0].Data))
-			ctx.AddEvent(&Event{
-				Name: "ClientRequest",
-				Node: leader,
-				Params: map[string]interface{}{
-					"request": request,
-					"leader":  leader,
-				},
-			})
+			ctx.AddEvent(NewClientRequestEvent(leader, request))
 			r.nodes[leader].Step(m)
 		}
 	} else {
@@ -155,13 +158,7 @@ func (r *RaftEnvironment) Tick(ctx *FuzzContext) []pb.Message {
 			r.storages[id].Append(ready.Entries)
 			result = append(result, ready.Messages...)
 			if len(ready.CommittedEntries) > 0 {
-				ctx.AddEvent(&Event{
-					Name: "AdvanceCommitIndex",
-					Node: id,
-					Params: map[string]interface{}{
-						"i": int(id),
-					},
-				})
+				ctx.AddEvent(NewAdvanceCommitIndexEvent(id))
 			}
 			node.Advance(ready)
 		}
@@ -178,29 +175,10 @@ func (r *RaftEnvironment) updateStates(ctx *FuzzContext) {
 		oldTerm := r.curStates[id].Term
 		newTerm := newStatus.Term
 		if old != new && new == raft.StateLeader {
-			ctx.AddEvent(&Event{
-				Name: "BecomeLeader",
-				Node: id,
-				Params: map[string]interface{}{
-					"node": id,
-				},
-			})
-			ctx.AddEvent(&Event{
-				Name: "ClientRequest",
-				Node: id,
-				Params: map[string]interface{}{
-					"request": 0,
-					"leader":  id,
-				},
-			})
+			ctx.AddEvent(NewBecomeLeaderEvent(id))
+			ctx.AddEvent(NewClientRequestEvent(id, 0))
 		} else if (old != new && new == raft.StateCandidate) || (oldTerm < newTerm && old == new && new == raft.StateCandidate) {
-			ctx.AddEvent(&Event{
-				Name: "Timeout",
-				Node: id,
-				Params: map[string]interface{}{
-					"node": id,
-				},
-			})
+			ctx.AddEvent(NewTimeoutEvent(id))
 		}
 		r.curStates[id] = newStatus
 	}
@@ -219,7 +197,7 @@ func (r *RaftEnvironment) Start(ctx *FuzzContext, nodeID uint64) {
 			Storage:                   storage,
 			MaxSizePerMsg:             1024 * 1024,
 			MaxInflightMsgs:           256,
-			Rand:                      nil,
+			Rand:                      r.newRand(nodeID),
 			MaxUncommittedEntriesSize: 1 << 30,
 			Logger:                    &raft.DefaultLogger{Logger: log.New(io.Discard, "", 0)},
 			CheckQuorum:               true,
@@ -495,6 +495,12 @@ func (rn *RawNode) Status() Status {
 	return status
 }
 
+// Timers returns the ticks elapsed since the election and heartbeat timers
+// were last reset, and the randomized election timeout of the node.
+func (rn *RawNode) Timers() (electionElapsed, heartbeatElapsed, randomizedElectionTimeout int) {
+	return rn.raft.electionElapsed, rn.raft.heartbeatElapsed, rn.raft.randomizedElectionTimeout
+}
+
 // BasicStatus returns a BasicStatus. Notably this does not contain the
 // Progress map; see WithProgress for an allocation-free way to inspect it.
 func (rn *RawNode) BasicStatus() BasicStatus {
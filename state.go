@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// NodeState is the snapshot of a single node after a step
+type NodeState struct {
+	ID        uint64
+	Active    bool
+	RaftState string
+	Term      uint64
+	Vote      uint64
+	Commit    uint64
+	Lead      uint64
+	Applied   uint64
+	LastIndex uint64
+	LastTerm  uint64
+}
+
+// MessageSummary counts the in-flight messages of one type on a channel
+type MessageSummary struct {
+	Type  string
+	From  uint64
+	To    uint64
+	Term  uint64
+	Count int
+}
+
+// EnvState is the snapshot of the whole environment after a step
+type EnvState struct {
+	Step     int
+	Nodes    []NodeState
+	Messages []MessageSummary
+}
+
+func (f *Fuzzer) snapshotState(step int) *EnvState {
+	env := f.raftEnvironment
+	state := &EnvState{
+		Step:     step,
+		Nodes:    make([]NodeState, 0, len(env.storages)),
+		Messages: make([]MessageSummary, 0),
+	}
+	for id, storage := range env.storages {
+		s := NodeState{ID: id}
+		if node, ok := env.nodes[id]; ok {
+			status := node.Status()
+			s.Active = true
+			s.RaftState = status.RaftState.String()
+			s.Term = status.Term
+			s.Vote = status.Vote
+			s.Commit = status.Commit
+			s.Lead = status.Lead
+			s.Applied = status.Applied
+		}
+		s.LastIndex, _ = storage.LastIndex()
+		s.LastTerm, _ = storage.Term(s.LastIndex)
+		state.Nodes = append(state.Nodes, s)
+	}
+	sort.Slice(state.Nodes, func(i, j int) bool {
+		return state.Nodes[i].ID < state.Nodes[j].ID
+	})
+
+	summaries := make(map[MessageSummary]int)
+	for _, q := range f.messageQueues {
+		for _, m := range q.q {
+			key := MessageSummary{Type: m.Type.String(), From: m.From, To: m.To, Term: m.Term}
+			summaries[key] += 1
+		}
+	}
+	for s, count := range summaries {
+		s.Count = count
+		state.Messages = append(state.Messages, s)
+	}
+	sort.Slice(state.Messages, func(i, j int) bool {
+		return state.Messages[i].less(state.Messages[j])
+	})
+	return state
+}
+
+func (m MessageSummary) less(other MessageSummary) bool {
+	if m.From != other.From {
+		return m.From < other.From
+	}
+	if m.To != other.To {
+		return m.To < other.To
+	}
+	if m.Type != other.Type {
+		return m.Type < other.Type
+	}
+	return m.Term < other.Term
+}
+
+// StateAbstraction maps a snapshot of the environment to the representation
+// used to distinguish states
+type StateAbstraction func(*EnvState) string
+
+// DefaultAbstraction keeps the role, hard state and log of every node along
+// with a summary of the in-flight messages
+func DefaultAbstraction() StateAbstraction {
+	return func(s *EnvState) string {
+		b := &strings.Builder{}
+		for _, n := range s.Nodes {
+			if !n.Active {
+				fmt.Fprintf(b, "%d:down[%d,%d];", n.ID, n.LastIndex, n.LastTerm)
+				continue
+			}
+			fmt.Fprintf(b, "%d:%s[t=%d,v=%d,c=%d,l=%d,log=%d/%d];", n.ID, n.RaftState, n.Term, n.Vote, n.Commit, n.Lead, n.LastIndex, n.LastTerm)
+		}
+		for _, m := range s.Messages {
+			fmt.Fprintf(b, "%s(%d->%d,t=%d)x%d;", m.Type, m.From, m.To, m.Term, m.Count)
+		}
+		return b.String()
+	}
+}
+
+// RolesTermsAbstraction keeps only the role and term of every node
+func RolesTermsAbstraction() StateAbstraction {
+	return func(s *EnvState) string {
+		b := &strings.Builder{}
+		for _, n := range s.Nodes {
+			if !n.Active {
+				fmt.Fprintf(b, "%d:down;", n.ID)
+				continue
+			}
+			fmt.Fprintf(b, "%d:%s[t=%d];", n.ID, n.RaftState, n.Term)
+		}
+		return b.String()
+	}
+}
+
+// LogCommitAbstraction keeps the role of every node, the number of its log
+// entries beyond its commit index and how far its commit index is ahead of the
+// lowest one
+func LogCommitAbstraction() StateAbstraction {
+	return func(s *EnvState) string {
+		minCommit := uint64(0)
+		first := true
+		for _, n := range s.Nodes {
+			if n.Active && (first || n.Commit < minCommit) {
+				minCommit = n.Commit
+				first = false
+			}
+		}
+		b := &strings.Builder{}
+		for _, n := range s.Nodes {
+			if !n.Active {
+				fmt.Fprintf(b, "%d:down;", n.ID)
+				continue
+			}
+			uncommitted := uint64(0)
+			if n.LastIndex > n.Commit {
+				uncommitted = n.LastIndex - n.Commit
+			}
+			fmt.Fprintf(b, "%d:%s[u=%d,c=+%d];", n.ID, n.RaftState, uncommitted, n.Commit-minCommit)
+		}
+		return b.String()
+	}
+}
+
+// VoteLeaderAbstraction keeps the (term, vote, leader) tuple of every node
+func VoteLeaderAbstraction() StateAbstraction {
+	return func(s *EnvState) string {
+		b := &strings.Builder{}
+		for _, n := range s.Nodes {
+			if !n.Active {
+				fmt.Fprintf(b, "%d:down;", n.ID)
+				continue
+			}
+			fmt.Fprintf(b, "%d:(%d,%d,%d);", n.ID, n.Term, n.Vote, n.Lead)
+		}
+		return b.String()
+	}
+}
+
+// TermDiffAbstraction keeps the role of every node and its term relative to
+// the lowest term, so that states differing only by a term offset coincide
+func TermDiffAbstraction() StateAbstraction {
+	return func(s *EnvState) string {
+		minTerm := uint64(0)
+		first := true
+		for _, n := range s.Nodes {
+			if n.Active && (first || n.Term < minTerm) {
+				minTerm = n.Term
+				first = false
+			}
+		}
+		b := &strings.Builder{}
+		for _, n := range s.Nodes {
+			if !n.Active {
+				fmt.Fprintf(b, "%d:down;", n.ID)
+				continue
+			}
+			fmt.Fprintf(b, "%d:%s[t=+%d];", n.ID, n.RaftState, n.Term-minTerm)
+		}
+		return b.String()
+	}
+}
+
+// GetAbstraction returns the state abstraction with the given name
+func GetAbstraction(name string) (StateAbstraction, error) {
+	switch name {
+	case "full":
+		return DefaultAbstraction(), nil
+	case "roles-terms":
+		return RolesTermsAbstraction(), nil
+	case "log-commit":
+		return LogCommitAbstraction(), nil
+	case "vote-leader":
+		return VoteLeaderAbstraction(), nil
+	case "term-diff":
+		return TermDiffAbstraction(), nil
+	}
+	return nil, fmt.Errorf("unknown state abstraction: %s", name)
+}
+
+func abstractStates(stateTrace *List[*EnvState], abstraction StateAbstraction) []State {
+	states := make([]State, stateTrace.Size())
+	for i, s := range stateTrace.Iter() {
+		repr := abstraction(s)
+		h := fnv.New64a()
+		h.Write([]byte(repr))
+		states[i] = State{
+			Repr: repr,
+			Key:  int64(h.Sum64()),
+		}
+	}
+	return states
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// tQuantiles95 are the 0.975 quantiles of the Student t distribution for 1
+// to 30 degrees of freedom
+var tQuantiles95 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+func tQuantile95(df int) float64 {
+	if df <= 0 {
+		return math.NaN()
+	}
+	if df <= len(tQuantiles95) {
+		return tQuantiles95[df-1]
+	}
+	return 1.96
+}
+
+// Summary describes a sample of runs, CILow and CIHigh bound the 95%
+// confidence interval of the mean
+type Summary struct {
+	N      int
+	Mean   float64
+	StdDev float64
+	CILow  float64
+	CIHigh float64
+	Median float64
+	Q1     float64
+	Q3     float64
+	IQR    float64
+	Min    float64
+	Max    float64
+}
+
+func summarize(xs []float64) Summary {
+	s := Summary{N: len(xs)}
+	if len(xs) == 0 {
+		return s
+	}
+	sorted := append([]float64{}, xs...)
+	sort.Float64s(sorted)
+	for _, x := range xs {
+		s.Mean += x
+	}
+	s.Mean /= float64(len(xs))
+	if len(xs) > 1 {
+		for _, x := range xs {
+			s.StdDev += (x - s.Mean) * (x - s.Mean)
+		}
+		s.StdDev = math.Sqrt(s.StdDev / float64(len(xs)-1))
+	}
+	margin := 0.0
+	if len(xs) > 1 {
+		margin = tQuantile95(len(xs)-1) * s.StdDev / math.Sqrt(float64(len(xs)))
+	}
+	s.CILow, s.CIHigh = s.Mean-margin, s.Mean+margin
+	s.Median = quantile(sorted, 0.5)
+	s.Q1 = quantile(sorted, 0.25)
+	s.Q3 = quantile(sorted, 0.75)
+	s.IQR = s.Q3 - s.Q1
+	s.Min, s.Max = sorted[0], sorted[len(sorted)-1]
+	return s
+}
+
+// quantile interpolates linearly between the closest ranks of the sorted
+// sample
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (pos-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+// ranks returns the ranks of the values of both samples, ties get the
+// average rank, along with the tie correction sum of t^3-t
+func ranks(xs, ys []float64) ([]float64, []float64, float64) {
+	type value struct {
+		v      float64
+		sample int
+		index  int
+	}
+	values := make([]value, 0, len(xs)+len(ys))
+	for i, x := range xs {
+		values = append(values, value{x, 0, i})
+	}
+	for i, y := range ys {
+		values = append(values, value{y, 1, i})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].v < values[j].v })
+	xRanks := make([]float64, len(xs))
+	yRanks := make([]float64, len(ys))
+	ties := 0.0
+	for i := 0; i < len(values); {
+		j := i
+		for j < len(values) && values[j].v == values[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for _, v := range values[i:j] {
+			if v.sample == 0 {
+				xRanks[v.index] = rank
+			} else {
+				yRanks[v.index] = rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+	return xRanks, yRanks, ties
+}
+
+// mannWhitneyU returns the U statistic of the first sample and the two sided
+// p-value of the samples coming from the same distribution. The p-value is
+// exact for small samples without ties and uses the normal approximation
+// otherwise.
+func mannWhitneyU(xs, ys []float64) (float64, float64) {
+	n1, n2 := len(xs), len(ys)
+	if n1 == 0 || n2 == 0 {
+		return 0, math.NaN()
+	}
+	xRanks, _, ties := ranks(xs, ys)
+	r1 := 0.0
+	for _, r := range xRanks {
+		r1 += r
+	}
+	u := r1 - float64(n1*(n1+1))/2
+	if ties == 0 && n1 <= 20 && n2 <= 20 {
+		return u, exactMannWhitneyP(n1, n2, u)
+	}
+	n := float64(n1 + n2)
+	mu := float64(n1*n2) / 2
+	sigma := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return u, 1
+	}
+	// Continuity correction
+	z := math.Max(math.Abs(u-mu)-0.5, 0) / sigma
+	return u, math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// exactMannWhitneyP sums the probabilities of the U values at least as far
+// from the mean as u, counting the arrangements of the ranks
+func exactMannWhitneyP(n1, n2 int, u float64) float64 {
+	maxU := n1 * n2
+	// counts[m][k] is the number of arrangements of m values of the first
+	// sample and n of the second with U = k, built up over n
+	counts := make([][]float64, n1+1)
+	for m := range counts {
+		counts[m] = make([]float64, maxU+1)
+		counts[m][0] = 1
+	}
+	for n := 1; n <= n2; n++ {
+		next := make([][]float64, n1+1)
+		next[0] = make([]float64, maxU+1)
+		next[0][0] = 1
+		for m := 1; m <= n1; m++ {
+			next[m] = make([]float64, maxU+1)
+			for k := 0; k <= m*n; k++ {
+				// The largest value belongs to the first sample, it is
+				// above all n of the second, or to the second
+				if k >= n {
+					next[m][k] += next[m-1][k-n]
+				}
+				next[m][k] += counts[m][k]
+			}
+		}
+		counts = next
+	}
+	total := 0.0
+	for _, c := range counts[n1] {
+		total += c
+	}
+	mu := float64(maxU) / 2
+	dist := math.Abs(u - mu)
+	extreme := 0.0
+	for k, c := range counts[n1] {
+		if math.Abs(float64(k)-mu) >= dist-1e-9 {
+			extreme += c
+		}
+	}
+	return math.Min(1, extreme/total)
+}
+
+// varghaDelaneyA12 is the probability that a value of the first sample is
+// larger than one of the second, counting ties as half
+func varghaDelaneyA12(xs, ys []float64) float64 {
+	if len(xs) == 0 || len(ys) == 0 {
+		return math.NaN()
+	}
+	u, _ := mannWhitneyU(xs, ys)
+	return u / float64(len(xs)*len(ys))
+}
+
+// effectSize names the magnitude of an A12 effect size with the thresholds of
+// Vargha and Delaney
+func effectSize(a12 float64) string {
+	d := math.Abs(a12 - 0.5)
+	switch {
+	case math.IsNaN(d):
+		return "-"
+	case d < 0.06:
+		return "negligible"
+	case d < 0.14:
+		return "small"
+	case d < 0.21:
+		return "medium"
+	}
+	return "large"
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func approxEqual(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestSummarize(t *testing.T) {
+	s := summarize([]float64{5, 1, 4, 2, 3})
+	if s.N != 5 || s.Mean != 3 || s.Median != 3 || s.Q1 != 2 || s.Q3 != 4 || s.IQR != 2 || s.Min != 1 || s.Max != 5 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+	// t(0.975, 4) * 1.5811 / sqrt(5)
+	if !approxEqual(s.CIHigh-s.Mean, 1.963, 0.001) || !approxEqual(s.Mean-s.CILow, 1.963, 0.001) {
+		t.Errorf("unexpected confidence interval: [%f, %f]", s.CILow, s.CIHigh)
+	}
+	if one := summarize([]float64{7}); one.CILow != 7 || one.CIHigh != 7 || one.Median != 7 {
+		t.Errorf("unexpected summary of one run: %+v", one)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	// Exact p-values of completely separated samples are 2/C(n1+n2, n1)
+	u, p := mannWhitneyU([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10})
+	if u != 0 || !approxEqual(p, 2.0/252, 1e-9) {
+		t.Errorf("expected U=0 and p=%f, got U=%f p=%f", 2.0/252, u, p)
+	}
+	u, p = mannWhitneyU([]float64{6, 5, 4}, []float64{1, 2, 3})
+	if u != 9 || !approxEqual(p, 0.1, 1e-9) {
+		t.Errorf("expected U=9 and p=0.1, got U=%f p=%f", u, p)
+	}
+	if _, p := mannWhitneyU([]float64{1, 4}, []float64{2, 3}); p != 1 {
+		t.Errorf("expected p=1 for U at the mean, got %f", p)
+	}
+	// With ties the normal approximation with tie and continuity corrections
+	u, p = mannWhitneyU([]float64{1, 1, 2, 5}, []float64{2, 3, 3, 4, 6})
+	if u != 4.5 || !approxEqual(p, 0.215, 0.001) {
+		t.Errorf("expected U=4.5 and p=0.215, got U=%f p=%f", u, p)
+	}
+}
+
+func TestVarghaDelaneyA12(t *testing.T) {
+	if a := varghaDelaneyA12([]float64{6, 7}, []float64{1, 2}); a != 1 || effectSize(a) != "large" {
+		t.Errorf("expected A12=1, got %f", a)
+	}
+	if a := varghaDelaneyA12([]float64{1, 2}, []float64{1, 2}); a != 0.5 || effectSize(a) != "negligible" {
+		t.Errorf("expected A12=0.5, got %f", a)
+	}
+	if a := varghaDelaneyA12([]float64{1, 3}, []float64{2, 2}); a != 0.5 {
+		t.Errorf("expected A12=0.5, got %f", a)
+	}
+}
+
+func TestFirstBugIteration(t *testing.T) {
+	if first := firstBugIteration([]BugRecord{{Iteration: 3}, {Iteration: 12}}); first != 3 {
+		t.Errorf("expected the first bug at iteration 3, got %d", first)
+	}
+	if first := firstBugIteration(nil); first != -1 {
+		t.Errorf("expected no bug, got %d", first)
+	}
+}
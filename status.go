@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxCurvePoints bounds the points of a coverage curve served, longer runs
+// are sampled
+const maxCurvePoints = 500
+
+// BenchmarkStatus is the progress of the current run of a benchmark
+type BenchmarkStatus struct {
+	Name           string
+	Run            int
+	Iteration      int
+	Iterations     int
+	Elapsed        string
+	ExecsPerSecond float64
+	Coverage       CoverageStats
+	Corpus         int
+	QueueSize      int
+	Errors         map[string]int
+	Bugs           int
+	BugSignatures  int
+	// Curves are the states covered over the iterations of every run
+	Curves [][]int
+
+	stats   *FuzzerStats
+	elapsed time.Duration
+}
+
+// CampaignStatus is served as the JSON status of a campaign
+type CampaignStatus struct {
+	Started    time.Time
+	Uptime     string
+	Current    string
+	Benchmarks []*BenchmarkStatus
+}
+
+// StatusServer serves the progress of the fuzzer runs over HTTP, it observes
+// the runs of every benchmark
+type StatusServer struct {
+	started    time.Time
+	current    string
+	benchmarks map[string]*BenchmarkStatus
+	lock       *sync.Mutex
+}
+
+func NewStatusServer() *StatusServer {
+	return &StatusServer{
+		started:    time.Now(),
+		benchmarks: make(map[string]*BenchmarkStatus),
+		lock:       new(sync.Mutex),
+	}
+}
+
+// statusObserver forwards the progress of one run to the server
+type statusObserver struct {
+	server    *StatusServer
+	benchmark string
+	run       int
+}
+
+func (o *statusObserver) Observe(p FuzzerProgress) {
+	o.server.observe(o.benchmark, o.run, p)
+}
+
+// Observer returns the observer of a run of the benchmark
+func (s *StatusServer) Observer(benchmark string, run int) FuzzerObserver {
+	return &statusObserver{server: s, benchmark: benchmark, run: run}
+}
+
+func (s *StatusServer) observe(benchmark string, run int, p FuzzerProgress) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	b, ok := s.benchmarks[benchmark]
+	if !ok {
+		b = &BenchmarkStatus{Name: benchmark, Run: run}
+		s.benchmarks[benchmark] = b
+	}
+	if b.Run != run || len(b.Curves) == 0 {
+		b.Curves = append(b.Curves, make([]int, 0, p.Iterations))
+		b.Run = run
+	}
+	curve := len(b.Curves) - 1
+	b.Curves[curve] = append(b.Curves[curve], p.Coverage.UniqueStates)
+	b.Iteration = p.Iteration
+	b.Iterations = p.Iterations
+	b.Coverage = p.Coverage
+	b.QueueSize = p.QueueSize
+	b.stats = p.Stats
+	b.elapsed = p.Elapsed
+	s.current = benchmark
+}
+
+// Status returns the status of the runs so far
+func (s *StatusServer) Status() *CampaignStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	status := &CampaignStatus{
+		Started:    s.started,
+		Uptime:     time.Since(s.started).Round(time.Second).String(),
+		Current:    s.current,
+		Benchmarks: make([]*BenchmarkStatus, 0, len(s.benchmarks)),
+	}
+	for _, b := range s.benchmarks {
+		copied := &BenchmarkStatus{
+			Name:       b.Name,
+			Run:        b.Run,
+			Iteration:  b.Iteration,
+			Iterations: b.Iterations,
+			Elapsed:    b.elapsed.Round(time.Millisecond).String(),
+			Coverage:   b.Coverage,
+			QueueSize:  b.QueueSize,
+			Errors:     make(map[string]int),
+			Curves:     make([][]int, len(b.Curves)),
+		}
+		if b.elapsed > 0 {
+			copied.ExecsPerSecond = float64(b.Iteration) / b.elapsed.Seconds()
+		}
+		if b.stats != nil {
+			stats := b.stats.Snapshot()
+			copied.Corpus = stats.Corpus
+			copied.Errors = stats.Errors
+			copied.Bugs = len(stats.Bugs)
+			copied.BugSignatures = len(uniqueSignatures(stats.Bugs))
+		}
+		for i, curve := range b.Curves {
+			copied.Curves[i] = sampleCurve(curve, maxCurvePoints)
+		}
+		status.Benchmarks = append(status.Benchmarks, copied)
+	}
+	sort.Slice(status.Benchmarks, func(i, j int) bool {
+		return status.Benchmarks[i].Name < status.Benchmarks[j].Name
+	})
+	return status
+}
+
+// sampleCurve keeps at most n evenly spaced points of the curve, and its last
+func sampleCurve(curve []int, n int) []int {
+	if len(curve) <= n {
+		return append([]int{}, curve...)
+	}
+	sampled := make([]int, 0, n+1)
+	step := float64(len(curve)) / float64(n)
+	for i := 0; i < n; i++ {
+		sampled = append(sampled, curve[int(float64(i)*step)])
+	}
+	return append(sampled, curve[len(curve)-1])
+}
+
+func (s *StatusServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Status())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(statusPage))
+	})
+	return mux
+}
+
+// Start serves the status on the address in the background
+func (s *StatusServer) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error serving the status: %s", err)
+	}
+	fmt.Printf("Serving the status on http://%s\n", listener.Addr())
+	go http.Serve(listener, s.Handler())
+	return nil
+}
+
+// statusPage polls the JSON status and draws the coverage curves
+const statusPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>raft-fuzzing status</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { padding: 2px 10px; text-align: right; border-bottom: 1px solid #ddd; }
+td:first-child, th:first-child { text-align: left; }
+.current { font-weight: bold; }
+svg { border: 1px solid #ccc; background: #fff; }
+</style>
+</head>
+<body>
+<h1>raft-fuzzing status</h1>
+<p id="summary">Waiting for the first iteration...</p>
+<table id="benchmarks"></table>
+<h2>States covered</h2>
+<svg id="curves" width="800" height="320"></svg>
+<div id="legend"></div>
+<h2>Errors</h2>
+<table id="errors"></table>
+<script>
+const colors = ["#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"];
+
+function cell(row, text, tag) {
+  const c = document.createElement(tag || "td");
+  c.textContent = text;
+  row.appendChild(c);
+}
+
+function render(status) {
+  document.getElementById("summary").textContent =
+    "Running for " + status.Uptime + (status.Current ? ", current benchmark " + status.Current : "");
+  const table = document.getElementById("benchmarks");
+  table.replaceChildren();
+  const header = table.insertRow();
+  ["benchmark", "run", "iteration", "execs/s", "elapsed", "states", "lines", "corpus", "queue", "errors", "bugs", "bug signatures"].forEach(h => cell(header, h, "th"));
+  const errors = document.getElementById("errors");
+  errors.replaceChildren();
+  let maxX = 1, maxY = 1;
+  status.Benchmarks.forEach(b => {
+    const row = table.insertRow();
+    if (b.Name === status.Current) row.className = "current";
+    const numErrors = Object.values(b.Errors || {}).reduce((a, c) => a + c, 0);
+    [b.Name, b.Run + 1, b.Iteration + "/" + b.Iterations, b.ExecsPerSecond.toFixed(1), b.Elapsed,
+     b.Coverage.UniqueStates, b.Coverage.CoveredLines, b.Corpus, b.QueueSize, numErrors, b.Bugs, b.BugSignatures].forEach(v => cell(row, v));
+    Object.entries(b.Errors || {}).forEach(([err, count]) => {
+      const r = errors.insertRow();
+      cell(r, b.Name + ": " + err);
+      cell(r, count);
+    });
+    b.Curves.forEach(c => {
+      maxX = Math.max(maxX, c.length - 1);
+      maxY = Math.max(maxY, ...c);
+    });
+  });
+
+  const svg = document.getElementById("curves");
+  const w = svg.width.baseVal.value, h = svg.height.baseVal.value, pad = 30;
+  let content = '<text x="4" y="14" font-size="11">' + maxY + '</text>';
+  const legend = [];
+  status.Benchmarks.forEach((b, k) => {
+    const color = colors[k % colors.length];
+    legend.push('<span style="color:' + color + '">&#9632; ' + b.Name + '</span>');
+    b.Curves.forEach((c, i) => {
+      const points = c.map((y, x) =>
+        (pad + x / Math.max(c.length - 1, 1) * (w - 2 * pad) * (c.length - 1) / maxX).toFixed(1) + "," +
+        (h - pad - y / maxY * (h - 2 * pad)).toFixed(1)).join(" ");
+      const opacity = i === b.Curves.length - 1 ? 1 : 0.35;
+      content += '<polyline fill="none" stroke="' + color + '" stroke-opacity="' + opacity + '" points="' + points + '"/>';
+    });
+  });
+  svg.innerHTML = content;
+  document.getElementById("legend").innerHTML = legend.join(" &nbsp; ");
+}
+
+function poll() {
+  fetch("status.json").then(r => r.json()).then(render).catch(() => {}).finally(() => setTimeout(poll, 2000));
+}
+poll();
+</script>
+</body>
+</html>
+`
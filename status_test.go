@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatusServer(t *testing.T) {
+	status := NewStatusServer()
+	server := httptest.NewServer(status.Handler())
+	defer server.Close()
+
+	tlc := newMockTLCServer(t)
+	config := testFuzzerConfig(NewTLCStateGuider(tlc.Client(), "", false), CombineMutators(NewSwapNodeMutator(5)))
+	for run := 0; run < 2; run++ {
+		config.Observer = status.Observer("tlcstate", run)
+		NewFuzzer(config).Run()
+	}
+
+	resp, err := http.Get(server.URL + "/status.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got := &CampaignStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Current != "tlcstate" || len(got.Benchmarks) != 1 {
+		t.Fatalf("unexpected status: %+v", got)
+	}
+	b := got.Benchmarks[0]
+	if b.Run != 1 || b.Iteration != 30 || b.Iterations != 30 {
+		t.Errorf("unexpected progress: %+v", b)
+	}
+	if len(b.Curves) != 2 || len(b.Curves[0]) != 30 || len(b.Curves[1]) != 30 {
+		t.Fatalf("expected a curve of 30 points per run, got %v", b.Curves)
+	}
+	if b.Curves[1][29] != b.Coverage.UniqueStates || b.Coverage.UniqueStates == 0 {
+		t.Errorf("curve does not end at the coverage: %v %+v", b.Curves[1], b.Coverage)
+	}
+	if b.Corpus == 0 || b.ExecsPerSecond <= 0 {
+		t.Errorf("expected a corpus and an execution rate: %+v", b)
+	}
+
+	resp, err = http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	page, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(page), "status.json") {
+		t.Error("expected the page to poll the status")
+	}
+}
+
+func TestSampleCurve(t *testing.T) {
+	curve := make([]int, 1000)
+	for i := range curve {
+		curve[i] = i
+	}
+	sampled := sampleCurve(curve, 100)
+	if len(sampled) != 101 || sampled[0] != 0 || sampled[100] != 999 {
+		t.Errorf("unexpected sample: %v", sampled)
+	}
+	if got := sampleCurve(curve[:10], 100); len(got) != 10 {
+		t.Errorf("expected short curves to be kept, got %v", got)
+	}
+}
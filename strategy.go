@@ -1,14 +1,42 @@
 package main
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 )
 
+// StrategyContext exposes the state of the current episode to a Strategy
+// when it is asked for the next scheduling choice
+type StrategyContext struct {
+	Step        int
+	Replicas    []uint64
+	MaxMessages int
+
+	fuzzer *Fuzzer
+}
+
+// Pending returns the number of undelivered messages from `from` to `to`
+func (s *StrategyContext) Pending(from, to uint64) int {
+	queue, ok := s.fuzzer.messageQueues[fmt.Sprintf("%d_%d", from, to)]
+	if !ok {
+		return 0
+	}
+	return queue.Size()
+}
+
+// Strategy decides the scheduling choices of an episode whenever there is no
+// choice to mimic
 type Strategy interface {
-	GetNextNode([]uint64) uint64
+	// Reset is called at the start of every episode
+	Reset(steps int)
+	GetNextNodeChoice(*StrategyContext) (uint64, uint64, int)
 	GetRandomBoolean() bool
 	GetRandomInteger(int) int
+	// GetCrashPoints returns the steps at which nodes are stopped and started
+	GetCrashPoints(steps int, quota int, replicas []uint64) (map[int]uint64, map[int]uint64)
+	// GetClientRequests returns the steps at which client requests are injected
+	GetClientRequests(steps int, requests int) map[int]int
 }
 
 type RandomStrategy struct {
@@ -23,11 +51,23 @@ func NewRandomStrategy() *RandomStrategy {
 	}
 }
 
+func (r *RandomStrategy) Seed(seed int64) {
+	r.rand = rand.New(rand.NewSource(seed))
+}
+
+func (r *RandomStrategy) Reset(_ int) {}
+
 func (r *RandomStrategy) GetNextNode(available []uint64) uint64 {
 	randIndex := r.rand.Intn(len(available))
 	return available[randIndex]
 }
 
+func (r *RandomStrategy) GetNextNodeChoice(ctx *StrategyContext) (uint64, uint64, int) {
+	from := r.GetNextNode(ctx.Replicas)
+	to := r.GetNextNode(ctx.Replicas)
+	return from, to, r.rand.Intn(ctx.MaxMessages)
+}
+
 func (r *RandomStrategy) GetRandomBoolean() bool {
 	return r.rand.Intn(2) == 0
 }
@@ -36,6 +76,28 @@ func (r *RandomStrategy) GetRandomInteger(max int) int {
 	return r.rand.Intn(max)
 }
 
+func (r *RandomStrategy) GetCrashPoints(steps int, quota int, replicas []uint64) (map[int]uint64, map[int]uint64) {
+	crashPoints := make(map[int]uint64)
+	startPoints := make(map[int]uint64)
+	for _, c := range sample(intRange(0, steps), quota, r.rand) {
+		node := r.GetNextNode(replicas)
+		crashPoints[c] = node
+		s := sample(intRange(c, steps), 1, r.rand)[0]
+		startPoints[s] = node
+	}
+	return crashPoints, startPoints
+}
+
+func (r *RandomStrategy) GetClientRequests(steps int, requests int) map[int]int {
+	clientRequests := make(map[int]int)
+	i := 1
+	for _, req := range sample(intRange(0, steps), requests, r.rand) {
+		clientRequests[req] = i
+		i++
+	}
+	return clientRequests
+}
+
 type RoundRobinStrategy struct {
 	*RandomStrategy
 	NumNodes int
@@ -52,6 +114,10 @@ func NewRoundRobinStrategy(numNodes int) *RoundRobinStrategy {
 	}
 }
 
+func (r *RoundRobinStrategy) Reset(_ int) {
+	r.curNode = 0
+}
+
 func (r *RoundRobinStrategy) GetNextNode(available []uint64) uint64 {
 	m := make(map[uint64]bool)
 	for _, n := range available {
@@ -66,3 +132,213 @@ func (r *RoundRobinStrategy) GetNextNode(available []uint64) uint64 {
 	r.curNode = (next + 1) % uint64(r.NumNodes)
 	return next
 }
+
+func (r *RoundRobinStrategy) GetNextNodeChoice(ctx *StrategyContext) (uint64, uint64, int) {
+	from := r.GetNextNode(ctx.Replicas)
+	to := r.GetNextNode(ctx.Replicas)
+	return from, to, r.rand.Intn(ctx.MaxMessages)
+}
+
+// pendingChannels returns the (from, to) pairs with undelivered messages in a
+// fixed order
+func pendingChannels(ctx *StrategyContext) [][2]uint64 {
+	channels := make([][2]uint64, 0)
+	for _, from := range ctx.Replicas {
+		for _, to := range ctx.Replicas {
+			if ctx.Pending(from, to) > 0 {
+				channels = append(channels, [2]uint64{from, to})
+			}
+		}
+	}
+	return channels
+}
+
+// DelayBoundedStrategy follows a deterministic round robin scheduler over the
+// message channels and deviates from it (delays the channel that would be
+// scheduled) at no more than MaxDelays randomly chosen steps of an episode
+type DelayBoundedStrategy struct {
+	*RandomStrategy
+	MaxDelays int
+
+	delayPoints map[int]bool
+	cursor      int
+}
+
+var _ Strategy = &DelayBoundedStrategy{}
+
+func NewDelayBoundedStrategy(maxDelays int) *DelayBoundedStrategy {
+	return &DelayBoundedStrategy{
+		RandomStrategy: NewRandomStrategy(),
+		MaxDelays:      maxDelays,
+		delayPoints:    make(map[int]bool),
+		cursor:         0,
+	}
+}
+
+func (d *DelayBoundedStrategy) Reset(steps int) {
+	d.delayPoints = make(map[int]bool)
+	for _, s := range sample(intRange(0, steps), d.MaxDelays, d.rand) {
+		d.delayPoints[s] = true
+	}
+	d.cursor = 0
+}
+
+func (d *DelayBoundedStrategy) GetNextNodeChoice(ctx *StrategyContext) (uint64, uint64, int) {
+	numReplicas := len(ctx.Replicas)
+	numChannels := numReplicas * numReplicas
+	candidates := make([]int, 0)
+	for i := 0; i < numChannels; i++ {
+		c := (d.cursor + i) % numChannels
+		if ctx.Pending(ctx.Replicas[c/numReplicas], ctx.Replicas[c%numReplicas]) > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(candidates) == 0 {
+		return d.RandomStrategy.GetNextNodeChoice(ctx)
+	}
+	next := candidates[0]
+	if _, ok := d.delayPoints[ctx.Step]; ok && len(candidates) > 1 {
+		next = candidates[1]
+	}
+	d.cursor = (next + 1) % numChannels
+	return ctx.Replicas[next/numReplicas], ctx.Replicas[next%numReplicas], ctx.MaxMessages
+}
+
+// POSStrategy implements partial order sampling. Every pending channel is
+// assigned a random priority, the channel with the highest priority is
+// delivered and the channels racing with it (same receiver) are assigned
+// fresh priorities
+type POSStrategy struct {
+	*RandomStrategy
+	priorities map[[2]uint64]float64
+}
+
+var _ Strategy = &POSStrategy{}
+
+func NewPOSStrategy() *POSStrategy {
+	return &POSStrategy{
+		RandomStrategy: NewRandomStrategy(),
+		priorities:     make(map[[2]uint64]float64),
+	}
+}
+
+func (p *POSStrategy) Reset(_ int) {
+	p.priorities = make(map[[2]uint64]float64)
+}
+
+func (p *POSStrategy) GetNextNodeChoice(ctx *StrategyContext) (uint64, uint64, int) {
+	channels := pendingChannels(ctx)
+	if len(channels) == 0 {
+		return p.RandomStrategy.GetNextNodeChoice(ctx)
+	}
+	var next [2]uint64
+	best := -1.0
+	for _, c := range channels {
+		priority, ok := p.priorities[c]
+		if !ok {
+			priority = p.rand.Float64()
+			p.priorities[c] = priority
+		}
+		if priority > best {
+			best = priority
+			next = c
+		}
+	}
+	for c := range p.priorities {
+		if c[1] == next[1] {
+			p.priorities[c] = p.rand.Float64()
+		}
+	}
+	return next[0], next[1], ctx.MaxMessages
+}
+
+// PCTStrategy implements probabilistic concurrency testing over the message
+// channels. Each channel gets a random initial priority and the highest
+// priority channel with pending messages is always delivered. At Depth-1
+// randomly chosen steps the priority of the channel about to be delivered is
+// lowered below all the initial priorities
+type PCTStrategy struct {
+	*RandomStrategy
+	Depth int
+
+	priorities   map[[2]uint64]int
+	changePoints map[int]int
+}
+
+var _ Strategy = &PCTStrategy{}
+
+func NewPCTStrategy(depth int) *PCTStrategy {
+	return &PCTStrategy{
+		RandomStrategy: NewRandomStrategy(),
+		Depth:          depth,
+		priorities:     make(map[[2]uint64]int),
+		changePoints:   make(map[int]int),
+	}
+}
+
+func (p *PCTStrategy) Reset(steps int) {
+	p.priorities = make(map[[2]uint64]int)
+	p.changePoints = make(map[int]int)
+	if p.Depth < 2 {
+		return
+	}
+	for i, s := range sample(intRange(0, steps), p.Depth-1, p.rand) {
+		p.changePoints[s] = i + 1
+	}
+}
+
+func (p *PCTStrategy) initPriorities(replicas []uint64) {
+	numReplicas := len(replicas)
+	for i, prio := range p.rand.Perm(numReplicas * numReplicas) {
+		channel := [2]uint64{replicas[i/numReplicas], replicas[i%numReplicas]}
+		p.priorities[channel] = p.Depth + prio
+	}
+}
+
+func (p *PCTStrategy) highestPending(channels [][2]uint64) [2]uint64 {
+	next := channels[0]
+	for _, c := range channels[1:] {
+		if p.priorities[c] > p.priorities[next] {
+			next = c
+		}
+	}
+	return next
+}
+
+func (p *PCTStrategy) GetNextNodeChoice(ctx *StrategyContext) (uint64, uint64, int) {
+	if len(p.priorities) == 0 {
+		p.initPriorities(ctx.Replicas)
+	}
+	channels := pendingChannels(ctx)
+	if len(channels) == 0 {
+		return p.RandomStrategy.GetNextNodeChoice(ctx)
+	}
+	next := p.highestPending(channels)
+	if prio, ok := p.changePoints[ctx.Step]; ok {
+		p.priorities[next] = prio
+		next = p.highestPending(channels)
+	}
+	return next[0], next[1], ctx.MaxMessages
+}
+
+// GetStrategy returns the strategy with the given name, seeded with the seed
+// unless it is zero
+func GetStrategy(name string, replicas int, maxDelays int, pctDepth int, seed int64) (Strategy, error) {
+	var s Strategy
+	switch name {
+	case "random":
+		s = NewRandomStrategy()
+	case "roundrobin":
+		s = NewRoundRobinStrategy(replicas)
+	case "delay":
+		s = NewDelayBoundedStrategy(maxDelays)
+	case "pos":
+		s = NewPOSStrategy()
+	case "pct":
+		s = NewPCTStrategy(pctDepth)
+	default:
+		return nil, fmt.Errorf("unknown strategy: %s", name)
+	}
+	seedRandom(s, seed)
+	return s, nil
+}
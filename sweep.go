@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// sweepParameters are the parameters a sweep can vary, named as in the
+// experiment config
+var sweepParameters = []string{"election_tick", "heartbeat_tick", "ticks_per_step", "max_messages", "crash_quota", "mut_per_trace", "reseed_frequency"}
+
+// SweepParameter is a parameter of the fuzzer and the values to try
+type SweepParameter struct {
+	Name   string
+	Values []int
+}
+
+// parseSweepParameter parses name=start:end[:step] or name=v1,v2,...
+func parseSweepParameter(spec string) (SweepParameter, error) {
+	name, values, ok := strings.Cut(spec, "=")
+	if !ok {
+		return SweepParameter{}, fmt.Errorf("expected name=range: %s", spec)
+	}
+	name = strings.TrimSpace(name)
+	known := false
+	for _, p := range sweepParameters {
+		known = known || p == name
+	}
+	if !known {
+		return SweepParameter{}, fmt.Errorf("unknown sweep parameter %s, expected one of %s", name, strings.Join(sweepParameters, ", "))
+	}
+	param := SweepParameter{Name: name}
+	if bounds := strings.Split(values, ":"); len(bounds) > 1 {
+		if len(bounds) > 3 {
+			return SweepParameter{}, fmt.Errorf("invalid range of %s: %s", name, values)
+		}
+		r := []int{0, 0, 1}
+		for i, b := range bounds {
+			v, err := strconv.Atoi(strings.TrimSpace(b))
+			if err != nil {
+				return SweepParameter{}, fmt.Errorf("invalid range of %s: %s", name, values)
+			}
+			r[i] = v
+		}
+		if r[2] <= 0 || r[1] < r[0] {
+			return SweepParameter{}, fmt.Errorf("invalid range of %s: %s", name, values)
+		}
+		for v := r[0]; v <= r[1]; v += r[2] {
+			param.Values = append(param.Values, v)
+		}
+		return param, nil
+	}
+	for _, s := range strings.Split(values, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return SweepParameter{}, fmt.Errorf("invalid value of %s: %s", name, s)
+		}
+		param.Values = append(param.Values, v)
+	}
+	return param, nil
+}
+
+// SweepSetting is one value of every swept parameter, in the order of the
+// parameters
+type SweepSetting []int
+
+func (s SweepSetting) label(params []SweepParameter) string {
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = fmt.Sprintf("%s=%d", p.Name, s[i])
+	}
+	return strings.Join(parts, ",")
+}
+
+// overrides turns the setting into overrides of the fuzzer config
+func (s SweepSetting) overrides(params []SweepParameter) (*ConfigOverrides, error) {
+	values := make(map[string]int, len(params))
+	for i, p := range params {
+		values[p.Name] = s[i]
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	o := &ConfigOverrides{}
+	if err := json.Unmarshal(data, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// gridSettings is the cartesian product of the values of the parameters
+func gridSettings(params []SweepParameter) []SweepSetting {
+	settings := []SweepSetting{{}}
+	for _, p := range params {
+		next := make([]SweepSetting, 0, len(settings)*len(p.Values))
+		for _, s := range settings {
+			for _, v := range p.Values {
+				setting := append(append(SweepSetting{}, s...), v)
+				next = append(next, setting)
+			}
+		}
+		settings = next
+	}
+	return settings
+}
+
+// randomSettings draws distinct settings uniformly from the grid
+func randomSettings(params []SweepParameter, samples int, r *rand.Rand) []SweepSetting {
+	grid := gridSettings(params)
+	if samples >= len(grid) {
+		return grid
+	}
+	settings := make([]SweepSetting, samples)
+	for i, j := range r.Perm(len(grid))[:samples] {
+		settings[i] = grid[j]
+	}
+	return settings
+}
+
+// validSetting rules out the configs the environment cannot run
+func validSetting(config *FuzzerConfig) error {
+	env := config.RaftEnvironmentConfig
+	switch {
+	case env.ElectionTick <= env.HeartbeatTick:
+		return fmt.Errorf("election tick must be greater than heartbeat tick")
+	case env.HeartbeatTick <= 0 || env.TicksPerStep <= 0:
+		return fmt.Errorf("heartbeat tick and ticks per step must be positive")
+	case config.MaxMessages <= 0 || config.ReseedFrequency <= 0:
+		return fmt.Errorf("max messages and reseed frequency must be positive")
+	case config.CrashQuota < 0 || config.MutPerTrace < 0:
+		return fmt.Errorf("crash quota and mutations per trace can not be negative")
+	}
+	return nil
+}
+
+// SweepResult is the outcome of the runs of one setting
+type SweepResult struct {
+	Setting       map[string]int
+	Label         string
+	Skipped       string `json:",omitempty"`
+	AverageStates float64
+	AverageLines  float64
+	// Bugs is the average number of buggy executions of a run
+	Bugs      float64
+	BuggyRuns int
+	Runs      int
+	Runtime   time.Duration
+}
+
+// Sweep runs the fuzzer with every setting of the parameters
+type Sweep struct {
+	Params   []SweepParameter
+	Settings []SweepSetting
+	Runs     int
+	// NewConfig returns the base config of a setting, the mutator is built for
+	// the config of the setting
+	NewConfig  func() *FuzzerConfig
+	NewMutator func(*FuzzerConfig) (Mutator, error)
+	Guider     Guider
+	// Status serves the progress of the runs when set, every setting is a
+	// benchmark
+	Status *StatusServer
+}
+
+func (s *Sweep) Run() ([]*SweepResult, error) {
+	results := make([]*SweepResult, 0, len(s.Settings))
+	for i, setting := range s.Settings {
+		label := setting.label(s.Params)
+		result := &SweepResult{Setting: make(map[string]int), Label: label}
+		for j, p := range s.Params {
+			result.Setting[p.Name] = setting[j]
+		}
+		results = append(results, result)
+
+		config := s.NewConfig()
+		overrides, err := setting.overrides(s.Params)
+		if err != nil {
+			return nil, err
+		}
+		overrides.apply(config)
+		if err := validSetting(config); err != nil {
+			fmt.Printf("Skipping setting %s: %s\n", label, err)
+			result.Skipped = err.Error()
+			continue
+		}
+		mutator, err := s.NewMutator(config)
+		if err != nil {
+			return nil, err
+		}
+		config.Mutator = mutator
+		config.Guider = s.Guider
+		setGuiderName(s.Guider, label)
+
+		fmt.Printf("Setting %d/%d: %s\n", i+1, len(s.Settings), label)
+		start := time.Now()
+		for run := 0; run < s.Runs; run++ {
+			if s.Status != nil {
+				config.Observer = s.Status.Observer(label, run)
+			}
+			fuzzer := NewFuzzer(config)
+			coverages := fuzzer.Run()
+			fmt.Println()
+			if len(coverages) > 0 {
+				final := coverages[len(coverages)-1]
+				result.AverageStates += float64(final.UniqueStates)
+				result.AverageLines += float64(final.CoveredLines)
+			}
+			bugs := len(fuzzer.stats.Snapshot().BuggyExecutions)
+			result.Bugs += float64(bugs)
+			if bugs > 0 {
+				result.BuggyRuns++
+			}
+			result.Runs++
+			s.Guider.Reset(label)
+			resetMutator(mutator)
+		}
+		result.Runtime = time.Since(start) / time.Duration(max(result.Runs, 1))
+		if result.Runs > 0 {
+			result.AverageStates /= float64(result.Runs)
+			result.AverageLines /= float64(result.Runs)
+			result.Bugs /= float64(result.Runs)
+		}
+	}
+	return results, nil
+}
+
+// sortSweepResults orders the results by coverage and then by bugs found,
+// skipped settings last
+func sortSweepResults(results []*SweepResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Skipped == "") != (b.Skipped == "") {
+			return a.Skipped == ""
+		}
+		if a.AverageStates != b.AverageStates {
+			return a.AverageStates > b.AverageStates
+		}
+		return a.Bugs > b.Bugs
+	})
+}
+
+func formatSweepResults(params []SweepParameter, results []*SweepResult) string {
+	b := &strings.Builder{}
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	header := make([]string, 0, len(params)+5)
+	for _, p := range params {
+		header = append(header, p.Name)
+	}
+	header = append(header, "avg states", "avg lines", "avg bugs", "buggy runs", "avg runtime")
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, r := range results {
+		row := make([]string, 0, len(header))
+		for _, p := range params {
+			row = append(row, strconv.Itoa(r.Setting[p.Name]))
+		}
+		if r.Skipped != "" {
+			row = append(row, "skipped: "+r.Skipped)
+		} else {
+			row = append(row,
+				strconv.FormatFloat(r.AverageStates, 'f', 1, 64),
+				strconv.FormatFloat(r.AverageLines, 'f', 1, 64),
+				strconv.FormatFloat(r.Bugs, 'f', 1, 64),
+				fmt.Sprintf("%d/%d", r.BuggyRuns, r.Runs),
+				r.Runtime.Round(time.Millisecond).String(),
+			)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// writeSweepResults saves the results as sweep.json and the table as
+// sweep.txt under savePath
+func writeSweepResults(savePath string, params []SweepParameter, results []*SweepResult) error {
+	if err := os.MkdirAll(savePath, 0777); err != nil {
+		return err
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path.Join(savePath, "sweep.json"), data, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(savePath, "sweep.txt"), []byte(formatSweepResults(params, results)), 0644)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSweepParameter(t *testing.T) {
+	for spec, expected := range map[string][]int{
+		"election_tick=10:30:10": {10, 20, 30},
+		"crash_quota=0:2":        {0, 1, 2},
+		"max_messages=2, 5,10":   {2, 5, 10},
+	} {
+		p, err := parseSweepParameter(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p.Values, expected) {
+			t.Errorf("expected %v for %s, got %v", expected, spec, p.Values)
+		}
+	}
+	for _, spec := range []string{"election_tick", "replicas=3", "ticks_per_step=5:1", "ticks_per_step=1:5:0", "max_messages=a,b"} {
+		if _, err := parseSweepParameter(spec); err == nil {
+			t.Errorf("expected an error for %s", spec)
+		}
+	}
+}
+
+func TestSweepSettings(t *testing.T) {
+	params := []SweepParameter{{Name: "election_tick", Values: []int{10, 20}}, {Name: "crash_quota", Values: []int{0, 1, 2}}}
+	grid := gridSettings(params)
+	if len(grid) != 6 || !reflect.DeepEqual(grid[0], SweepSetting{10, 0}) || !reflect.DeepEqual(grid[5], SweepSetting{20, 2}) {
+		t.Fatalf("unexpected grid: %v", grid)
+	}
+	random := randomSettings(params, 4, rand.New(rand.NewSource(1)))
+	seen := make(map[string]bool)
+	for _, s := range random {
+		seen[s.label(params)] = true
+	}
+	if len(random) != 4 || len(seen) != 4 {
+		t.Errorf("expected 4 distinct settings, got %v", random)
+	}
+	if len(randomSettings(params, 10, rand.New(rand.NewSource(1)))) != 6 {
+		t.Error("expected the whole grid when drawing more samples than settings")
+	}
+	o, err := grid[5].overrides(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := testFuzzerConfig(nil, nil)
+	o.apply(config)
+	if config.RaftEnvironmentConfig.ElectionTick != 20 || config.CrashQuota != 2 || config.MaxMessages != 5 {
+		t.Errorf("unexpected config of %s: %+v", grid[5].label(params), config)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	params := []SweepParameter{{Name: "heartbeat_tick", Values: []int{2, 10}}, {Name: "max_messages", Values: []int{3}}}
+	sweep := &Sweep{
+		Params:   params,
+		Settings: gridSettings(params),
+		Runs:     2,
+		NewConfig: func() *FuzzerConfig {
+			config := testFuzzerConfig(nil, nil)
+			config.Iterations = 5
+			return config
+		},
+		NewMutator: func(config *FuzzerConfig) (Mutator, error) {
+			return getCompareMutator("combined", config, 0)
+		},
+		Guider: NewNativeStateGuider(DefaultAbstraction(), "", false),
+	}
+	results, err := sweep.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortSweepResults(results)
+	if len(results) != 2 || results[0].Runs != 2 || results[0].AverageStates == 0 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	// The heartbeat tick of 10 is not below the election tick
+	if results[1].Skipped == "" || results[1].Setting["heartbeat_tick"] != 10 {
+		t.Errorf("expected the invalid setting to be skipped: %+v", results[1])
+	}
+	table := formatSweepResults(params, results)
+	if !strings.Contains(table, "/2") {
+		t.Errorf("expected the buggy runs in the table:\n%s", table)
+	}
+	if !strings.Contains(table, "skipped") {
+		t.Errorf("expected the skipped setting in the table:\n%s", table)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// TraceLimits are the bounds of the fuzzer configuration a trace has to stay
+// within
+type TraceLimits struct {
+	Steps          int
+	CrashQuota     int
+	NumberRequests int
+	MaxMessages    int
+}
+
+func NewTraceLimits(config *FuzzerConfig) TraceLimits {
+	return TraceLimits{
+		Steps:          config.Steps,
+		CrashQuota:     config.CrashQuota,
+		NumberRequests: config.NumberRequests,
+		MaxMessages:    config.MaxMessages,
+	}
+}
+
+// crashTimeline returns the stops and starts of every node ordered by step,
+// a node is stopped before it is started within a step
+func crashTimeline(trace *List[*SchedulingChoice]) map[uint64][]*SchedulingChoice {
+	timeline := make(map[uint64][]*SchedulingChoice)
+	for _, ch := range trace.Iter() {
+		if ch.Type == StopNode || ch.Type == StartNode {
+			timeline[ch.Node] = append(timeline[ch.Node], ch)
+		}
+	}
+	for _, choices := range timeline {
+		sort.SliceStable(choices, func(i, j int) bool {
+			if choices[i].Step != choices[j].Step {
+				return choices[i].Step < choices[j].Step
+			}
+			return choices[i].Type == StopNode && choices[j].Type == StartNode
+		})
+	}
+	return timeline
+}
+
+// dropIneffectiveCrashes copies the trace without the stops of stopped nodes
+// and the starts of running nodes. The fuzzer records them but they do not
+// change the execution.
+func dropIneffectiveCrashes(trace *List[*SchedulingChoice]) *List[*SchedulingChoice] {
+	drop := make(map[*SchedulingChoice]bool)
+	for _, choices := range crashTimeline(trace) {
+		down := false
+		for _, ch := range choices {
+			if (ch.Type == StopNode) == down {
+				drop[ch] = true
+				continue
+			}
+			down = ch.Type == StopNode
+		}
+	}
+	return copyTrace(trace, func(ch *SchedulingChoice) bool {
+		return !drop[ch]
+	})
+}
+
+// crashesWellFormed checks that every start follows a stop of the node, that
+// at most one node is stopped and one started in a step and that the stops
+// are within the quota
+func crashesWellFormed(trace *List[*SchedulingChoice], limits TraceLimits) bool {
+	stops := make(map[int]bool)
+	starts := make(map[int]bool)
+	for _, ch := range trace.Iter() {
+		if ch.Type != StopNode && ch.Type != StartNode {
+			continue
+		}
+		if ch.Step < 0 || ch.Step >= limits.Steps {
+			return false
+		}
+		steps := stops
+		if ch.Type == StartNode {
+			steps = starts
+		}
+		if steps[ch.Step] {
+			return false
+		}
+		steps[ch.Step] = true
+	}
+	if len(stops) > limits.CrashQuota {
+		return false
+	}
+	for _, choices := range crashTimeline(trace) {
+		for i, ch := range choices {
+			if (ch.Type == StopNode) != (i%2 == 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mutateAttempts bounds the number of random candidates a mutator tries
+// before giving up on a trace
+const mutateAttempts = 10
+
+// CrashTimingMutator moves a stop or a start of a node by up to MaxShift steps
+type CrashTimingMutator struct {
+	MaxShift int
+	Limits   TraceLimits
+	r        *rand.Rand
+}
+
+var _ Mutator = &CrashTimingMutator{}
+
+func NewCrashTimingMutator(maxShift int, limits TraceLimits) *CrashTimingMutator {
+	return &CrashTimingMutator{
+		MaxShift: maxShift,
+		Limits:   limits,
+		r:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (c *CrashTimingMutator) Seed(seed int64) {
+	c.r = rand.New(rand.NewSource(seed))
+}
+
+func (c *CrashTimingMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
+	trace = dropIneffectiveCrashes(trace)
+	crashes := make([]int, 0)
+	for i, ch := range trace.Iter() {
+		if ch.Type == StopNode || ch.Type == StartNode {
+			crashes = append(crashes, i)
+		}
+	}
+	if len(crashes) == 0 || c.MaxShift < 1 {
+		return nil, false
+	}
+	for attempt := 0; attempt < mutateAttempts; attempt++ {
+		newTrace := copyTrace(trace, defaultCopyFilter())
+		ch, _ := newTrace.Get(crashes[c.r.Intn(len(crashes))])
+		shift := 1 + c.r.Intn(c.MaxShift)
+		if c.r.Intn(2) == 0 {
+			shift = -shift
+		}
+		ch.Step += shift
+		if crashesWellFormed(newTrace, c.Limits) {
+			return newTrace, true
+		}
+	}
+	return nil, false
+}
+
+// CrashPairMutator either adds a stop and a later start of a node or removes
+// a stop along with the start that follows it
+type CrashPairMutator struct {
+	Nodes []uint64
+	// MaxDowntime bounds the number of steps between an added stop and start
+	MaxDowntime int
+	Limits      TraceLimits
+	r           *rand.Rand
+}
+
+var _ Mutator = &CrashPairMutator{}
+
+func NewCrashPairMutator(nodes []uint64, maxDowntime int, limits TraceLimits) *CrashPairMutator {
+	return &CrashPairMutator{
+		Nodes:       nodes,
+		MaxDowntime: maxDowntime,
+		Limits:      limits,
+		r:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (c *CrashPairMutator) Seed(seed int64) {
+	c.r = rand.New(rand.NewSource(seed))
+}
+
+func (c *CrashPairMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
+	trace = dropIneffectiveCrashes(trace)
+	if c.r.Intn(2) == 0 {
+		if newTrace, ok := c.remove(trace); ok {
+			return newTrace, true
+		}
+	}
+	return c.add(trace)
+}
+
+func (c *CrashPairMutator) add(trace *List[*SchedulingChoice]) (*List[*SchedulingChoice], bool) {
+	if len(c.Nodes) == 0 || c.Limits.Steps < 2 || c.MaxDowntime < 1 {
+		return nil, false
+	}
+	for attempt := 0; attempt < mutateAttempts; attempt++ {
+		node := c.Nodes[c.r.Intn(len(c.Nodes))]
+		stop := c.r.Intn(c.Limits.Steps - 1)
+		start := min(stop+1+c.r.Intn(c.MaxDowntime), c.Limits.Steps-1)
+		newTrace := insertAtStep(trace, &SchedulingChoice{Type: StopNode, Node: node, Step: stop})
+		newTrace = insertAtStep(newTrace, &SchedulingChoice{Type: StartNode, Node: node, Step: start})
+		// insertAtStep replaces choices at the same step, so a changed number
+		// of crashes means another crash was lost
+		if countCrashChoices(newTrace) == countCrashChoices(trace)+2 && crashesWellFormed(newTrace, c.Limits) {
+			return newTrace, true
+		}
+	}
+	return nil, false
+}
+
+func (c *CrashPairMutator) remove(trace *List[*SchedulingChoice]) (*List[*SchedulingChoice], bool) {
+	timeline := crashTimeline(trace)
+	stops := make([][2]*SchedulingChoice, 0)
+	for _, choices := range timeline {
+		for i, ch := range choices {
+			if ch.Type != StopNode {
+				continue
+			}
+			var start *SchedulingChoice = nil
+			if i+1 < len(choices) {
+				start = choices[i+1]
+			}
+			stops = append(stops, [2]*SchedulingChoice{ch, start})
+		}
+	}
+	if len(stops) == 0 {
+		return nil, false
+	}
+	pair := stops[c.r.Intn(len(stops))]
+	newTrace := copyTrace(trace, func(ch *SchedulingChoice) bool {
+		return ch != pair[0] && ch != pair[1]
+	})
+	return newTrace, crashesWellFormed(newTrace, c.Limits)
+}
+
+func countCrashChoices(trace *List[*SchedulingChoice]) int {
+	count := 0
+	for _, ch := range trace.Iter() {
+		if ch.Type == StopNode || ch.Type == StartNode {
+			count++
+		}
+	}
+	return count
+}
+
+// RequestTimingMutator moves a client request to another step by up to
+// MaxShift steps. Requests are never added or removed.
+type RequestTimingMutator struct {
+	MaxShift int
+	Limits   TraceLimits
+	r        *rand.Rand
+}
+
+var _ Mutator = &RequestTimingMutator{}
+
+func NewRequestTimingMutator(maxShift int, limits TraceLimits) *RequestTimingMutator {
+	return &RequestTimingMutator{
+		MaxShift: maxShift,
+		Limits:   limits,
+		r:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (m *RequestTimingMutator) Seed(seed int64) {
+	m.r = rand.New(rand.NewSource(seed))
+}
+
+func (m *RequestTimingMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
+	requests := make([]int, 0)
+	steps := make(map[int]bool)
+	for i, ch := range trace.Iter() {
+		if ch.Type == ClientRequest {
+			requests = append(requests, i)
+			steps[ch.Step] = true
+		}
+	}
+	if len(requests) == 0 || m.MaxShift < 1 {
+		return nil, false
+	}
+	for attempt := 0; attempt < mutateAttempts; attempt++ {
+		i := requests[m.r.Intn(len(requests))]
+		request, _ := trace.Get(i)
+		step := request.Step + m.r.Intn(2*m.MaxShift+1) - m.MaxShift
+		if step < 0 || step >= m.Limits.Steps || steps[step] {
+			continue
+		}
+		newTrace := copyTrace(trace, defaultCopyFilter())
+		moved, _ := newTrace.Get(i)
+		moved.Step = step
+		return newTrace, true
+	}
+	return nil, false
+}
+
+// MaxMessagesMutator draws new values for the number of messages delivered by
+// NumChanges node choices, below the MaxMessages limit like the random strategy
+type MaxMessagesMutator struct {
+	NumChanges int
+	Limits     TraceLimits
+	r          *rand.Rand
+}
+
+var _ Mutator = &MaxMessagesMutator{}
+
+func NewMaxMessagesMutator(changes int, limits TraceLimits) *MaxMessagesMutator {
+	return &MaxMessagesMutator{
+		NumChanges: changes,
+		Limits:     limits,
+		r:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (m *MaxMessagesMutator) Seed(seed int64) {
+	m.r = rand.New(rand.NewSource(seed))
+}
+
+func (m *MaxMessagesMutator) Mutate(trace *List[*SchedulingChoice], _ *List[*Event]) (*List[*SchedulingChoice], bool) {
+	nodeChoices := nodeChoiceIndices(trace)
+	if len(nodeChoices) == 0 || m.Limits.MaxMessages < 1 {
+		return nil, false
+	}
+	newTrace := copyTrace(trace, defaultCopyFilter())
+	for _, i := range sample(nodeChoices, m.NumChanges, m.r) {
+		ch, _ := newTrace.Get(i)
+		ch.MaxMessages = m.r.Intn(m.Limits.MaxMessages)
+	}
+	return newTrace, true
+}
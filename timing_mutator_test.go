@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCrashesWellFormed(t *testing.T) {
+	limits := TraceLimits{Steps: 10, CrashQuota: 2, NumberRequests: 1, MaxMessages: 5}
+	trace := NewList[*SchedulingChoice]()
+	trace.Append(&SchedulingChoice{Type: StartNode, Node: 1, Step: 0})
+	trace.Append(&SchedulingChoice{Type: StopNode, Node: 1, Step: 2})
+	trace.Append(&SchedulingChoice{Type: StopNode, Node: 1, Step: 3})
+	trace.Append(&SchedulingChoice{Type: StartNode, Node: 1, Step: 5})
+	if crashesWellFormed(trace, limits) {
+		t.Error("expected a start of a running node to be rejected")
+	}
+	cleaned := dropIneffectiveCrashes(trace)
+	if cleaned.Size() != 2 || !crashesWellFormed(cleaned, limits) {
+		t.Errorf("expected a stop and a start to remain, got %d choices", cleaned.Size())
+	}
+
+	cleaned.Append(&SchedulingChoice{Type: StopNode, Node: 2, Step: 2})
+	if crashesWellFormed(cleaned, limits) {
+		t.Error("expected two stops in a step to be rejected")
+	}
+	cleaned.Set(2, &SchedulingChoice{Type: StopNode, Node: 2, Step: 4})
+	cleaned.Append(&SchedulingChoice{Type: StopNode, Node: 3, Step: 6})
+	if crashesWellFormed(cleaned, limits) {
+		t.Error("expected the crash quota to be respected")
+	}
+}
+
+func TestTimingMutators(t *testing.T) {
+	config := testFuzzerConfig(nil, &EmptyMutator{})
+	limits := NewTraceLimits(config)
+	mutators := map[string]Mutator{
+		"crashTiming":   NewCrashTimingMutator(5, limits),
+		"crashPair":     NewCrashPairMutator([]uint64{1, 2, 3}, 10, limits),
+		"requestTiming": NewRequestTimingMutator(10, limits),
+		"maxMessages":   NewMaxMessagesMutator(5, limits),
+	}
+	f := NewFuzzer(config)
+	for name, m := range mutators {
+		t.Run(name, func(t *testing.T) {
+			mutations := 0
+			for i := 0; i < 20; i++ {
+				trace, events, _ := f.RunIteration("timing", nil)
+				mutated, ok := m.Mutate(trace, events)
+				if !ok {
+					continue
+				}
+				mutations++
+				if (name == "crashTiming" || name == "crashPair") && !crashesWellFormed(mutated, limits) {
+					t.Fatal("expected well formed crashes")
+				}
+				if countChoices(mutated, ClientRequest) != countChoices(trace, ClientRequest) {
+					t.Fatal("expected the number of client requests to be unchanged")
+				}
+				steps := make(map[int]bool)
+				for _, ch := range mutated.Iter() {
+					if ch.Type == ClientRequest {
+						if steps[ch.Step] || ch.Step < 0 || ch.Step >= limits.Steps {
+							t.Fatalf("unexpected client request step %d", ch.Step)
+						}
+						steps[ch.Step] = true
+					}
+					if ch.Type == Node && (ch.MaxMessages < 0 || ch.MaxMessages >= limits.MaxMessages) {
+						t.Fatalf("unexpected max messages %d", ch.MaxMessages)
+					}
+				}
+				f.RunIteration("mutated", mutated)
+			}
+			if mutations == 0 {
+				t.Error("expected some mutations to succeed")
+			}
+		})
+	}
+}
@@ -6,45 +6,266 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
 type TLCResponse struct {
 	States []string
 	Keys   []int64
+	// Lengths contains the number of states of each trace when multiple
+	// traces are sent in one request
+	Lengths []int `json:",omitempty"`
+}
+
+type TLCClientConfig struct {
+	Addrs      []string
+	Timeout    time.Duration
+	Retries    int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// BatchSize is the maximum number of traces sent in one request
+	BatchSize int
+}
+
+func DefaultTLCClientConfig(addrs ...string) TLCClientConfig {
+	return TLCClientConfig{
+		Addrs:      addrs,
+		Timeout:    30 * time.Second,
+		Retries:    5,
+		Backoff:    500 * time.Millisecond,
+		MaxBackoff: 30 * time.Second,
+		BatchSize:  1,
+	}
+}
+
+// tlcRequestError is returned when the server rejects the request itself,
+// sending it again will not help
+type tlcRequestError struct {
+	status string
+	msg    string
+}
+
+func (e *tlcRequestError) Error() string {
+	return fmt.Sprintf("tlc rejected the trace: %s: %s", e.status, e.msg)
+}
+
+type tlcServer struct {
+	addr     string
+	inflight int
+	failures int
 }
 
 type TLCClient struct {
 	ClientAddr string
+	config     TLCClientConfig
+	client     *http.Client
+	servers    []*tlcServer
+	next       int
+	noBatching bool
+
+	lock *sync.Mutex
 }
 
+// NewTLCClient creates a client with the default configuration for a comma
+// separated list of server addresses
 func NewTLCClient(addr string) *TLCClient {
+	return NewTLCClientWithConfig(DefaultTLCClientConfig(strings.Split(addr, ",")...))
+}
+
+func NewTLCClientWithConfig(config TLCClientConfig) *TLCClient {
+	if config.BatchSize < 1 {
+		config.BatchSize = 1
+	}
+	servers := make([]*tlcServer, len(config.Addrs))
+	for i, addr := range config.Addrs {
+		servers[i] = &tlcServer{addr: strings.TrimSpace(addr)}
+	}
 	return &TLCClient{
-		ClientAddr: addr,
+		ClientAddr: strings.Join(config.Addrs, ","),
+		config:     config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		servers: servers,
+		next:    0,
+		lock:    new(sync.Mutex),
 	}
 }
 
 func (c *TLCClient) SendTrace(trace *List[*Event]) ([]State, error) {
-	trace.Append(&Event{Reset: true})
-	data, err := json.Marshal(trace)
+	return c.sendTrace(trace, c.config.Retries)
+}
+
+// TrySendTrace sends the trace once without retrying, to find out whether an
+// unreachable server is back
+func (c *TLCClient) TrySendTrace(trace *List[*Event]) ([]State, error) {
+	return c.sendTrace(trace, 0)
+}
+
+func (c *TLCClient) sendTrace(trace *List[*Event], retries int) ([]State, error) {
+	states, err := c.sendBatch([]*List[*Event]{trace}, retries)
+	if err != nil {
+		return []State{}, err
+	}
+	return states[0], nil
+}
+
+// SendTraces sends the traces to the TLC servers, BatchSize traces per
+// request, and returns the states of each trace
+func (c *TLCClient) SendTraces(traces []*List[*Event]) ([][]State, error) {
+	result := make([][]State, 0, len(traces))
+	for start := 0; start < len(traces); start += c.config.BatchSize {
+		end := min(start+c.config.BatchSize, len(traces))
+		states, err := c.sendBatch(traces[start:end], c.config.Retries)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, states...)
+	}
+	return result, nil
+}
+
+func (c *TLCClient) sendBatch(traces []*List[*Event], retries int) ([][]State, error) {
+	c.lock.Lock()
+	noBatching := c.noBatching
+	c.lock.Unlock()
+	if len(traces) > 1 && noBatching {
+		result := make([][]State, len(traces))
+		for i, t := range traces {
+			states, err := c.sendBatch([]*List[*Event]{t}, retries)
+			if err != nil {
+				return result[:i], err
+			}
+			result[i] = states[0]
+		}
+		return result, nil
+	}
+
+	events := make([]*Event, 0)
+	for _, t := range traces {
+		if err := ValidateEventTrace(t); err != nil {
+			return nil, fmt.Errorf("invalid event trace: %s", err)
+		}
+		events = append(events, t.Iter()...)
+		events = append(events, &Event{Reset: true})
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling json: %s", err)
+	}
+	tlcResponse, err := c.execute(data, retries)
+	if err != nil {
+		return nil, err
+	}
+	if len(tlcResponse.Keys) < len(tlcResponse.States) {
+		return nil, fmt.Errorf("error parsing tlc response: %d keys for %d states", len(tlcResponse.Keys), len(tlcResponse.States))
+	}
+	states := make([]State, len(tlcResponse.States))
+	for i, s := range tlcResponse.States {
+		states[i] = State{Repr: s, Key: tlcResponse.Keys[i]}
+	}
+	if len(traces) == 1 {
+		return [][]State{states}, nil
+	}
+
+	if len(tlcResponse.Lengths) != len(traces) {
+		// The server does not split batched responses, send one at a time
+		c.lock.Lock()
+		c.noBatching = true
+		c.lock.Unlock()
+		return c.sendBatch(traces, retries)
+	}
+	result := make([][]State, len(traces))
+	offset := 0
+	for i, l := range tlcResponse.Lengths {
+		if l < 0 || offset+l > len(states) {
+			return nil, fmt.Errorf("error parsing tlc response: lengths exceed %d states", len(states))
+		}
+		result[i] = states[offset : offset+l]
+		offset += l
+	}
+	return result, nil
+}
+
+// execute posts the data to the least loaded server, retrying with
+// exponential backoff when the request fails
+func (c *TLCClient) execute(data []byte, retries int) (*TLCResponse, error) {
+	var lastErr error
+	backoff := c.config.Backoff
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff = time.Duration(min(int(backoff*2), int(c.config.MaxBackoff)))
+		}
+		server := c.pickServer()
+		response, err := c.post(server.addr, data)
+		_, rejected := err.(*tlcRequestError)
+		c.lock.Lock()
+		server.inflight -= 1
+		if err != nil && !rejected {
+			server.failures += 1
+		} else {
+			server.failures = 0
+		}
+		c.lock.Unlock()
+		if err == nil {
+			return response, nil
+		}
+		if rejected {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (c *TLCClient) pickServer() *tlcServer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var best *tlcServer
+	for i := 0; i < len(c.servers); i++ {
+		s := c.servers[(c.next+i)%len(c.servers)]
+		if best == nil || s.failures < best.failures || (s.failures == best.failures && s.inflight < best.inflight) {
+			best = s
+		}
+	}
+	c.next = (c.next + 1) % len(c.servers)
+	best.inflight += 1
+	return best
+}
+
+func (c *TLCClient) post(addr string, data []byte) (*TLCResponse, error) {
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/execute", bytes.NewBuffer(data))
 	if err != nil {
-		return []State{}, fmt.Errorf("error marshalling json: %s", err)
+		return nil, fmt.Errorf("error creating request: %s", err)
 	}
-	res, err := http.Post("http://"+c.ClientAddr+"/execute", "application/json", bytes.NewBuffer(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventSchemaVersionHeader, EventSchemaVersion)
+	res, err := c.client.Do(req)
 	if err != nil {
-		return []State{}, fmt.Errorf("error sending trace to tlc: %s", err)
+		return nil, fmt.Errorf("error sending trace to tlc: %s", err)
 	}
 	defer res.Body.Close()
 	resData, err := io.ReadAll(res.Body)
 	if err != nil {
-		return []State{}, fmt.Errorf("error reading response from tlc: %s", err)
+		return nil, fmt.Errorf("error reading response from tlc: %s", err)
+	}
+	if res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+		return nil, &tlcRequestError{status: res.Status, msg: strings.TrimSpace(string(resData))}
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error response from tlc: %s: %s", res.Status, strings.TrimSpace(string(resData)))
 	}
 	tlcResponse := &TLCResponse{}
 	if err = json.Unmarshal(resData, tlcResponse); err != nil {
-		return []State{}, fmt.Errorf("error parsing tlc response: %s", err)
+		return nil, fmt.Errorf("error parsing tlc response: %s", err)
 	}
-	result := make([]State, len(tlcResponse.States))
-	for i, s := range tlcResponse.States {
-		result[i] = State{Repr: s, Key: tlcResponse.Keys[i]}
-	}
-	return result, nil
+	return tlcResponse, nil
 }
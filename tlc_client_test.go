@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+)
+
+func testEventTrace() *List[*Event] {
+	events := NewList[*Event]()
+	events.Append(NewTimeoutEvent(1))
+	events.Append(NewBecomeLeaderEvent(1))
+	events.Append(NewClientRequestEvent(1, 1))
+	return events
+}
+
+func TestTLCClientSendTrace(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	events := testEventTrace()
+	states, err := tlc.Client().SendTrace(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != events.Size()+1 {
+		t.Errorf("expected %d states, got %d", events.Size()+1, len(states))
+	}
+	if events.Size() != 3 {
+		t.Errorf("expected the trace not to be modified, got %d events", events.Size())
+	}
+}
+
+func TestTLCClientRetries(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	tlc.FailNext(2)
+	if _, err := tlc.Client().SendTrace(testEventTrace()); err != nil {
+		t.Fatalf("expected the request to be retried: %s", err)
+	}
+	if tlc.Requests() != 3 {
+		t.Errorf("expected 3 requests, got %d", tlc.Requests())
+	}
+
+	config := DefaultTLCClientConfig(tlc.Addr())
+	config.Backoff = 0
+	config.Retries = 1
+	tlc.FailNext(2)
+	if _, err := NewTLCClientWithConfig(config).SendTrace(testEventTrace()); err == nil {
+		t.Error("expected an error once the retries are exhausted")
+	}
+}
+
+func testBatchTraces(t *testing.T, tlc *mockTLCServer) ([]*List[*Event], [][]State) {
+	traces := make([]*List[*Event], 0)
+	single := make([][]State, 0)
+	for i := 1; i <= 6; i++ {
+		events := NewList[*Event]()
+		for _, e := range testEventTrace().Iter()[:i%3+1] {
+			events.Append(e)
+		}
+		traces = append(traces, events)
+		states, err := tlc.Client().SendTrace(events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		single = append(single, states)
+	}
+	return traces, single
+}
+
+func checkBatchStates(t *testing.T, batched, single [][]State) {
+	if len(batched) != len(single) {
+		t.Fatalf("expected states for %d traces, got %d", len(single), len(batched))
+	}
+	for i := range single {
+		if len(batched[i]) != len(single[i]) {
+			t.Fatalf("trace %d: expected %d states, got %d", i, len(single[i]), len(batched[i]))
+		}
+		for j := range single[i] {
+			if batched[i][j].Key != single[i][j].Key {
+				t.Errorf("trace %d: state %d differs", i, j)
+			}
+		}
+	}
+}
+
+func TestTLCClientBatching(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	config := DefaultTLCClientConfig(tlc.Addr())
+	config.BatchSize = 4
+	client := NewTLCClientWithConfig(config)
+
+	traces, single := testBatchTraces(t, tlc)
+	requests := tlc.Requests()
+	batched, err := client.SendTraces(traces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlc.Requests()-requests != 2 {
+		t.Errorf("expected 2 batched requests, got %d", tlc.Requests()-requests)
+	}
+	checkBatchStates(t, batched, single)
+}
+
+func TestTLCClientBatchingFallback(t *testing.T) {
+	tlc := newMockTLCServer(t)
+	tlc.NoLengths()
+	config := DefaultTLCClientConfig(tlc.Addr())
+	config.BatchSize = 4
+	client := NewTLCClientWithConfig(config)
+
+	traces, single := testBatchTraces(t, tlc)
+	requests := tlc.Requests()
+	batched, err := client.SendTraces(traces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One rejected batch, then one request per trace
+	if tlc.Requests()-requests != len(traces)+1 {
+		t.Errorf("expected %d requests, got %d", len(traces)+1, tlc.Requests()-requests)
+	}
+	checkBatchStates(t, batched, single)
+}
+
+func TestTLCClientPooling(t *testing.T) {
+	first := newMockTLCServer(t)
+	second := newMockTLCServer(t)
+	config := DefaultTLCClientConfig(first.Addr(), second.Addr(), "127.0.0.1:1")
+	config.Backoff = 0
+	client := NewTLCClientWithConfig(config)
+	for i := 0; i < 10; i++ {
+		if _, err := client.SendTrace(testEventTrace()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first.Requests() == 0 || second.Requests() == 0 {
+		t.Errorf("expected requests on both servers, got %d and %d", first.Requests(), second.Requests())
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockTLCServer implements the /execute protocol of the TLC server. Instead of
+// running the model it computes deterministic pseudo-states from the events:
+// the role and term of every node, the number of client requests and commits.
+type mockTLCServer struct {
+	server *httptest.Server
+
+	lock     *sync.Mutex
+	requests int
+	failNext int
+	// version is the event schema version accepted by the server
+	version string
+	// divergeOn is the name of an event the model cannot take
+	divergeOn string
+	// noLengths makes the server answer batches like a server that does not
+	// split the states of the traces
+	noLengths bool
+}
+
+func newMockTLCServer(t *testing.T) *mockTLCServer {
+	m := &mockTLCServer{
+		lock:    new(sync.Mutex),
+		version: EventSchemaVersion,
+	}
+	m.server = httptest.NewServer(http.HandlerFunc(m.handle))
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockTLCServer) Addr() string {
+	return strings.TrimPrefix(m.server.URL, "http://")
+}
+
+func (m *mockTLCServer) Client() *TLCClient {
+	config := DefaultTLCClientConfig(m.Addr())
+	config.Backoff = 0
+	return NewTLCClientWithConfig(config)
+}
+
+// FailNext makes the next n requests fail with an internal server error
+func (m *mockTLCServer) FailNext(n int) {
+	m.lock.Lock()
+	m.failNext = n
+	m.lock.Unlock()
+}
+
+// DivergeOn makes the model stop following a trace at the first event with
+// the given name
+func (m *mockTLCServer) DivergeOn(name string) {
+	m.lock.Lock()
+	m.divergeOn = name
+	m.lock.Unlock()
+}
+
+// NoLengths makes the server leave out the lengths of batched traces
+func (m *mockTLCServer) NoLengths() {
+	m.lock.Lock()
+	m.noLengths = true
+	m.lock.Unlock()
+}
+
+func (m *mockTLCServer) Requests() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.requests
+}
+
+func (m *mockTLCServer) handle(w http.ResponseWriter, r *http.Request) {
+	m.lock.Lock()
+	m.requests += 1
+	fail := m.failNext > 0
+	if fail {
+		m.failNext -= 1
+	}
+	version := m.version
+	divergeOn := m.divergeOn
+	noLengths := m.noLengths
+	m.lock.Unlock()
+	if fail {
+		http.Error(w, "mock failure", http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Path != "/execute" {
+		http.NotFound(w, r)
+		return
+	}
+	if v := r.Header.Get(EventSchemaVersionHeader); v != version {
+		http.Error(w, fmt.Sprintf("unsupported event schema version %q", v), http.StatusConflict)
+		return
+	}
+
+	events := make([]*Event, 0)
+	if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := &TLCResponse{
+		States:  make([]string, 0),
+		Keys:    make([]int64, 0),
+		Lengths: make([]int, 0),
+	}
+	model := newMockModel()
+	length := 1
+	stuck := false
+	response.add(model.state())
+	for _, e := range events {
+		if e.Reset {
+			if !noLengths {
+				response.Lengths = append(response.Lengths, length)
+			}
+			model = newMockModel()
+			length = 1
+			stuck = false
+			response.add(model.state())
+			continue
+		}
+		if stuck = stuck || e.Name == divergeOn; stuck {
+			continue
+		}
+		model.apply(e)
+		length += 1
+		response.add(model.state())
+	}
+	// The states following the last reset do not belong to any trace
+	response.States = response.States[:len(response.States)-1]
+	response.Keys = response.Keys[:len(response.Keys)-1]
+	if len(response.Lengths) < 2 {
+		response.Lengths = nil
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (r *TLCResponse) add(state string) {
+	h := fnv.New64a()
+	h.Write([]byte(state))
+	r.States = append(r.States, state)
+	r.Keys = append(r.Keys, int64(h.Sum64()))
+}
+
+type mockNode struct {
+	role    string
+	term    int
+	commits int
+}
+
+type mockModel struct {
+	nodes    map[int]*mockNode
+	requests int
+}
+
+func newMockModel() *mockModel {
+	return &mockModel{
+		nodes: make(map[int]*mockNode),
+	}
+}
+
+func (m *mockModel) node(params map[string]interface{}, key string) *mockNode {
+	id, ok := params[key].(float64)
+	if !ok {
+		return nil
+	}
+	if _, ok := m.nodes[int(id)]; !ok {
+		m.nodes[int(id)] = &mockNode{role: "follower"}
+	}
+	return m.nodes[int(id)]
+}
+
+func (m *mockModel) apply(e *Event) {
+	switch e.Name {
+	case "Timeout":
+		if n := m.node(e.Params, "node"); n != nil {
+			n.role = "candidate"
+			n.term = min(n.term+1, 4)
+		}
+	case "BecomeLeader":
+		if n := m.node(e.Params, "node"); n != nil {
+			n.role = "leader"
+		}
+	case "Remove":
+		if n := m.node(e.Params, "i"); n != nil {
+			n.role = "down"
+		}
+	case "Add":
+		if n := m.node(e.Params, "i"); n != nil {
+			n.role = "follower"
+		}
+	case "AdvanceCommitIndex":
+		if n := m.node(e.Params, "i"); n != nil {
+			n.commits = min(n.commits+1, 3)
+		}
+	case "ClientRequest":
+		m.requests = min(m.requests+1, 3)
+	case "DeliverMessage":
+		n := m.node(e.Params, "to")
+		term, ok := e.Params["term"].(float64)
+		if n != nil && ok && int(term) > n.term && n.role != "down" {
+			n.term = min(int(term), 4)
+			n.role = "follower"
+		}
+	}
+}
+
+func (m *mockModel) state() string {
+	ids := make([]int, 0, len(m.nodes))
+	for id := range m.nodes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	b := &strings.Builder{}
+	for _, id := range ids {
+		n := m.nodes[id]
+		fmt.Fprintf(b, "/\\ node%d = <<%s, %d, %d>>\n", id, n.role, n.term, n.commits)
+	}
+	fmt.Fprintf(b, "/\\ requests = %d", m.requests)
+	return b.String()
+}
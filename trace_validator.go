@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TraceIssue is a kind of inconsistency of a scheduling trace
+type TraceIssue string
+
+const (
+	// The trace has fewer node choices than steps, the strategy would pick
+	// the rest
+	ShortTrace TraceIssue = "short_trace"
+	// The trace has more node choices than steps
+	LongTrace TraceIssue = "long_trace"
+	// A node choice of a node that does not exist or with a negative or too
+	// large number of messages
+	InvalidNodeChoice TraceIssue = "invalid_node_choice"
+	// A choice keyed by step outside the episode
+	StepOutOfRange TraceIssue = "step_out_of_range"
+	// Two choices of the same type at one step, only one of them is applied
+	DuplicateStep TraceIssue = "duplicate_step"
+	// A start of a running node or a stop of a stopped node
+	DanglingCrash TraceIssue = "dangling_crash"
+	// More stops than the crash quota
+	CrashQuotaExceeded TraceIssue = "crash_quota_exceeded"
+	// More client requests than configured
+	TooManyRequests TraceIssue = "too_many_requests"
+	// Two client requests with the same number
+	DuplicateRequest TraceIssue = "duplicate_request"
+)
+
+// TraceValidator checks that mutated traces are consistent with the fuzzer
+// configuration and repairs them
+type TraceValidator struct {
+	Limits   TraceLimits
+	Replicas []uint64
+	r        *rand.Rand
+}
+
+func NewTraceValidator(limits TraceLimits, replicas []uint64) *TraceValidator {
+	return &TraceValidator{
+		Limits:   limits,
+		Replicas: replicas,
+		r:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (v *TraceValidator) Seed(seed int64) {
+	v.r = rand.New(rand.NewSource(seed))
+}
+
+func (v *TraceValidator) validNode(node uint64) bool {
+	// Node 0 is the client
+	return node <= uint64(len(v.Replicas))
+}
+
+func (v *TraceValidator) validNodeChoice(ch *SchedulingChoice) bool {
+	return v.validNode(ch.From) && v.validNode(ch.To) && ch.MaxMessages >= 0 && ch.MaxMessages <= v.Limits.MaxMessages
+}
+
+// Validate returns the issues of the trace, once for every occurrence
+func (v *TraceValidator) Validate(trace *List[*SchedulingChoice]) []TraceIssue {
+	_, issues := v.normalize(trace)
+	return issues
+}
+
+// Normalize returns a repaired copy of the trace along with the issues found.
+// Choices that would not be applied are dropped, clashing request numbers are
+// renumbered and the node choices are cut or padded with random ones to the
+// number of steps.
+func (v *TraceValidator) Normalize(trace *List[*SchedulingChoice]) (*List[*SchedulingChoice], []TraceIssue) {
+	return v.normalize(trace)
+}
+
+func (v *TraceValidator) normalize(trace *List[*SchedulingChoice]) (*List[*SchedulingChoice], []TraceIssue) {
+	issues := make([]TraceIssue, 0)
+	drop := make(map[*SchedulingChoice]bool)
+	seen := make(map[SchedulingChoiceType]map[int]bool)
+	for _, ch := range trace.Iter() {
+		if !isStepChoice(ch) {
+			continue
+		}
+		if ch.Step < 0 || ch.Step >= v.Limits.Steps {
+			issues = append(issues, StepOutOfRange)
+			drop[ch] = true
+			continue
+		}
+		if seen[ch.Type] == nil {
+			seen[ch.Type] = make(map[int]bool)
+		}
+		// The fuzzer keeps the last choice of a type at a step
+		if seen[ch.Type][ch.Step] {
+			issues = append(issues, DuplicateStep)
+		}
+		seen[ch.Type][ch.Step] = true
+	}
+	last := make(map[SchedulingChoiceType]map[int]*SchedulingChoice)
+	for _, ch := range trace.Iter() {
+		if isStepChoice(ch) && !drop[ch] {
+			if last[ch.Type] == nil {
+				last[ch.Type] = make(map[int]*SchedulingChoice)
+			}
+			if prev, ok := last[ch.Type][ch.Step]; ok {
+				drop[prev] = true
+			}
+			last[ch.Type][ch.Step] = ch
+		}
+	}
+
+	// Crashes and restarts in step order, of existing nodes and within quota
+	stops := 0
+	for _, choices := range crashTimeline(keptChoices(trace, drop)) {
+		down := false
+		for _, ch := range choices {
+			switch {
+			case ch.Node == 0 || !v.validNode(ch.Node):
+				issues = append(issues, InvalidNodeChoice)
+				drop[ch] = true
+			case (ch.Type == StopNode) == down:
+				issues = append(issues, DanglingCrash)
+				drop[ch] = true
+			default:
+				down = ch.Type == StopNode
+				if down {
+					stops++
+				}
+			}
+		}
+	}
+	if stops > v.Limits.CrashQuota {
+		issues = append(issues, CrashQuotaExceeded)
+		v.dropExtraCrashes(trace, drop)
+	}
+
+	requests := make([]*SchedulingChoice, 0)
+	for _, ch := range trace.Iter() {
+		if ch.Type == ClientRequest && !drop[ch] {
+			requests = append(requests, ch)
+		}
+	}
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].Step < requests[j].Step })
+	if len(requests) > v.Limits.NumberRequests {
+		issues = append(issues, TooManyRequests)
+		for _, ch := range requests[v.Limits.NumberRequests:] {
+			drop[ch] = true
+		}
+		requests = requests[:v.Limits.NumberRequests]
+	}
+	renumber := make(map[*SchedulingChoice]int)
+	numbers := make(map[int]bool)
+	maxRequest := 0
+	for _, ch := range requests {
+		maxRequest = max(maxRequest, ch.Request)
+	}
+	for _, ch := range requests {
+		if numbers[ch.Request] {
+			issues = append(issues, DuplicateRequest)
+			maxRequest++
+			renumber[ch] = maxRequest
+		}
+		numbers[ch.Request] = true
+	}
+
+	newTrace := NewList[*SchedulingChoice]()
+	nodeChoices := 0
+	for _, ch := range trace.Iter() {
+		if drop[ch] {
+			continue
+		}
+		newCh := ch.Copy()
+		switch ch.Type {
+		case Node:
+			nodeChoices++
+			if nodeChoices > v.Limits.Steps {
+				continue
+			}
+			if !v.validNodeChoice(ch) {
+				issues = append(issues, InvalidNodeChoice)
+				newCh = v.randomNodeChoice()
+			}
+		case ClientRequest:
+			if n, ok := renumber[ch]; ok {
+				newCh.Request = n
+			}
+		}
+		newTrace.Append(newCh)
+	}
+	if nodeChoices > v.Limits.Steps {
+		issues = append(issues, LongTrace)
+	}
+	if nodeChoices < v.Limits.Steps {
+		issues = append(issues, ShortTrace)
+		for ; nodeChoices < v.Limits.Steps; nodeChoices++ {
+			newTrace.Append(v.randomNodeChoice())
+		}
+	}
+	return newTrace, issues
+}
+
+// dropExtraCrashes drops the stops beyond the quota, in step order, along
+// with the start that follows each of them
+func (v *TraceValidator) dropExtraCrashes(trace *List[*SchedulingChoice], drop map[*SchedulingChoice]bool) {
+	pairs := make([][2]*SchedulingChoice, 0)
+	for _, choices := range crashTimeline(keptChoices(trace, drop)) {
+		for i, ch := range choices {
+			if ch.Type != StopNode {
+				continue
+			}
+			pair := [2]*SchedulingChoice{ch, nil}
+			if i+1 < len(choices) {
+				pair[1] = choices[i+1]
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i][0].Step < pairs[j][0].Step })
+	for _, pair := range pairs[v.Limits.CrashQuota:] {
+		drop[pair[0]] = true
+		if pair[1] != nil {
+			drop[pair[1]] = true
+		}
+	}
+}
+
+// keptChoices returns the choices that are not dropped without copying them
+func keptChoices(trace *List[*SchedulingChoice], drop map[*SchedulingChoice]bool) *List[*SchedulingChoice] {
+	kept := NewList[*SchedulingChoice]()
+	for _, ch := range trace.Iter() {
+		if !drop[ch] {
+			kept.Append(ch)
+		}
+	}
+	return kept
+}
+
+func (v *TraceValidator) randomNodeChoice() *SchedulingChoice {
+	ch := &SchedulingChoice{Type: Node}
+	if len(v.Replicas) > 0 {
+		ch.From = v.Replicas[v.r.Intn(len(v.Replicas))]
+		ch.To = v.Replicas[v.r.Intn(len(v.Replicas))]
+	}
+	if v.Limits.MaxMessages > 0 {
+		ch.MaxMessages = v.r.Intn(v.Limits.MaxMessages)
+	}
+	return ch
+}
+
+// newIssues returns the issues that occur more often than in the parent
+func newIssues(issues, parentIssues []TraceIssue) []TraceIssue {
+	parent := make(map[TraceIssue]int)
+	for _, issue := range parentIssues {
+		parent[issue]++
+	}
+	introduced := make([]TraceIssue, 0)
+	for _, issue := range issues {
+		if parent[issue] > 0 {
+			parent[issue]--
+			continue
+		}
+		introduced = append(introduced, issue)
+	}
+	return introduced
+}
+
+// InvalidTraceStats counts the traces of a mutator that had to be repaired
+type InvalidTraceStats struct {
+	Mutations int
+	Invalid   int
+	Issues    map[TraceIssue]int
+}
+
+// NamedMutator is implemented by composite mutators to tell which of their
+// parts produced the last trace
+type NamedMutator interface {
+	LastMutation() string
+}
+
+func mutatorName(m Mutator) string {
+	if n, ok := m.(NamedMutator); ok {
+		return n.LastMutation()
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", m), "*main.")
+}
+
+func formatInvalidTraceStats(stats map[string]*InvalidTraceStats) string {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b := &strings.Builder{}
+	for _, name := range names {
+		s := stats[name]
+		if s.Invalid == 0 {
+			continue
+		}
+		issues := make([]string, 0, len(s.Issues))
+		for issue, count := range s.Issues {
+			issues = append(issues, fmt.Sprintf("%s=%d", issue, count))
+		}
+		sort.Strings(issues)
+		fmt.Fprintf(b, "%-24s %d/%d invalid (%s)\n", name, s.Invalid, s.Mutations, strings.Join(issues, ", "))
+	}
+	return b.String()
+}
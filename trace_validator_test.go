@@ -0,0 +1,124 @@
+package main
+
+import (
+	"testing"
+)
+
+func countIssues(issues []TraceIssue, issue TraceIssue) int {
+	count := 0
+	for _, i := range issues {
+		if i == issue {
+			count++
+		}
+	}
+	return count
+}
+
+func TestTraceValidator(t *testing.T) {
+	limits := TraceLimits{Steps: 4, CrashQuota: 1, NumberRequests: 2, MaxMessages: 5}
+	v := NewTraceValidator(limits, []uint64{1, 2, 3})
+
+	trace := NewList[*SchedulingChoice]()
+	trace.Append(&SchedulingChoice{Type: StartNode, Node: 2, Step: 0})
+	trace.Append(&SchedulingChoice{Type: Node, From: 1, To: 2, MaxMessages: 1})
+	trace.Append(&SchedulingChoice{Type: StopNode, Node: 1, Step: 1})
+	trace.Append(&SchedulingChoice{Type: StopNode, Node: 3, Step: 1})
+	trace.Append(&SchedulingChoice{Type: Node, From: 1, To: 7, MaxMessages: 1})
+	trace.Append(&SchedulingChoice{Type: StartNode, Node: 1, Step: 2})
+	trace.Append(&SchedulingChoice{Type: StopNode, Node: 2, Step: 2})
+	trace.Append(&SchedulingChoice{Type: Node, From: 2, To: 1, MaxMessages: 9})
+	trace.Append(&SchedulingChoice{Type: ClientRequest, Request: 1, Step: 0})
+	trace.Append(&SchedulingChoice{Type: ClientRequest, Request: 1, Step: 2})
+	trace.Append(&SchedulingChoice{Type: ClientRequest, Request: 2, Step: 3})
+	trace.Append(&SchedulingChoice{Type: DuplicateMessage, From: 1, To: 2, Step: 4})
+
+	repaired, issues := v.Normalize(trace)
+	expected := map[TraceIssue]int{
+		ShortTrace:         1,
+		InvalidNodeChoice:  2,
+		StepOutOfRange:     1,
+		DuplicateStep:      1,
+		DanglingCrash:      2,
+		CrashQuotaExceeded: 1,
+		TooManyRequests:    1,
+		DuplicateRequest:   1,
+	}
+	for issue, count := range expected {
+		if n := countIssues(issues, issue); n != count {
+			t.Errorf("expected %d %s, got %d in %v", count, issue, n, issues)
+		}
+	}
+	if len(issues) != 10 {
+		t.Errorf("unexpected issues %v", issues)
+	}
+	if remaining := v.Validate(repaired); len(remaining) != 0 {
+		t.Errorf("expected the repaired trace to be valid, got %v", remaining)
+	}
+	if countChoices(repaired, Node) != limits.Steps {
+		t.Errorf("expected %d node choices, got %d", limits.Steps, countChoices(repaired, Node))
+	}
+	for _, ch := range repaired.Iter() {
+		switch ch.Type {
+		case StopNode:
+			if ch.Node != 3 || ch.Step != 1 {
+				t.Errorf("expected only the last stop of step 1 to be kept, got %v", ch)
+			}
+		case StartNode:
+			t.Errorf("expected the starts of running nodes to be dropped, got %v", ch)
+		case ClientRequest:
+			if ch.Step == 3 {
+				t.Errorf("expected the request beyond the limit to be dropped, got %v", ch)
+			}
+			if ch.Step == 2 && ch.Request != 2 {
+				t.Errorf("expected the clashing request to be renumbered, got %v", ch)
+			}
+		}
+	}
+	if trace.Size() != 12 {
+		t.Error("expected the trace to be unchanged")
+	}
+}
+
+func TestTraceValidatorRecordedTraces(t *testing.T) {
+	config := testFuzzerConfig(nil, &EmptyMutator{})
+	f := NewFuzzer(config)
+	for i := 0; i < 10; i++ {
+		trace, _, _ := f.RunIteration("recorded", nil)
+		repaired, issues := f.validator.Normalize(trace)
+		for _, issue := range issues {
+			// The strategy starts running nodes and stops stopped ones
+			if issue != DanglingCrash {
+				t.Errorf("unexpected issue %s of a recorded trace", issue)
+			}
+		}
+		if remaining := f.validator.Validate(repaired); len(remaining) != 0 {
+			t.Errorf("expected the repaired trace to be valid, got %v", remaining)
+		}
+	}
+}
+
+func TestFuzzerRepairsMutations(t *testing.T) {
+	config := testFuzzerConfig(NewNativeStateGuider(DefaultAbstraction(), "", false), nil)
+	b := NewBanditMutator(
+		BanditArm{Name: "skipNode(3)", Mutator: NewSkipNodeMutator(3)},
+		BanditArm{Name: "swapNode(5)", Mutator: NewSwapNodeMutator(5)},
+	)
+	config.Mutator = b
+	f := NewFuzzer(config)
+	f.Run()
+	stats := f.stats.Snapshot().InvalidTraces
+	skip, ok := stats["skipNode(3)"]
+	if !ok || skip.Issues[ShortTrace] == 0 {
+		t.Fatalf("expected the shortened traces of the skip mutator to be reported, got %v", skip)
+	}
+	if swap := stats["swapNode(5)"]; swap != nil && swap.Invalid != 0 {
+		t.Errorf("expected the swaps to be valid, got %v", swap.Issues)
+	}
+	successes := 0
+	for _, s := range b.MutatorStats() {
+		successes += s.Successes
+	}
+	if successes == 0 {
+		t.Error("expected the feedback of repaired traces to reach the bandit")
+	}
+}
@@ -10,6 +10,9 @@ type Event struct {
 	Node   uint64 `json:"-"`
 	Params map[string]interface{}
 	Reset  bool
+	// Step is the step of the episode in which the event occurred, it maps
+	// the event back to the scheduling choices of that step
+	Step int `json:"-"`
 }
 
 var (
@@ -19,6 +22,9 @@ var (
 	StartNode     SchedulingChoiceType = "StartNode"
 	StopNode      SchedulingChoiceType = "StopNode"
 	ClientRequest SchedulingChoiceType = "ClientRequest"
+	// DuplicateMessage delivers the last append of the From->To channel
+	// again after the node choice of Step
+	DuplicateMessage SchedulingChoiceType = "DuplicateMessage"
 )
 
 type SchedulingChoiceType string
@@ -167,16 +173,16 @@ func sample(l []int, size int, r *rand.Rand) []int {
 	if size >= len(l) {
 		return l
 	}
+	// The samples are kept in the order drawn, a seeded source gives the
+	// same samples
 	indexes := make(map[int]bool)
-	for len(indexes) < size {
+	samples := make([]int, 0, size)
+	for len(samples) < size {
 		i := r.Intn(len(l))
-		indexes[i] = true
-	}
-	samples := make([]int, size)
-	i := 0
-	for k := range indexes {
-		samples[i] = l[k]
-		i++
+		if !indexes[i] {
+			indexes[i] = true
+			samples = append(samples, l[i])
+		}
 	}
 	return samples
 }